@@ -1,27 +1,97 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"os/user"
 
+	"github.com/amit9838/taskmanager/internal/config"
 	"github.com/amit9838/taskmanager/internal/storage"
 	"github.com/amit9838/taskmanager/internal/task"
 	"github.com/amit9838/taskmanager/pkg/cli"
 )
 
 func main() {
-	// Initialize storage
-	fileStorage := storage.NewJSONStorage("tasks.json")
+	// Parse global flags that appear before the command
+	globals := flag.NewFlagSet("tm", flag.ContinueOnError)
+	globals.Usage = func() {} // the help command prints the full usage
+	file := globals.String("file", "", "path to the tasks file (overrides $TM_FILE)")
+	lockTimeout := globals.String("lock-timeout", "", "how long to wait for another tm to finish (overrides $TM_LOCK_TIMEOUT)")
+	args := os.Args[1:]
+	if err := globals.Parse(args); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		args = []string{"help"}
+	} else {
+		args = globals.Args()
+	}
+
+	// Resolve the data location: --file, then $TM_FILE, then the XDG data directory
+	path, err := storage.ResolvePath(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving tasks file: %v\n", err)
+		os.Exit(1)
+	}
+
+	timeout, err := storage.ResolveLockTimeout(*lockTimeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	configPath, err := config.ResolvePath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving config file: %v\n", err)
+		os.Exit(1)
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Initialize storage: tasks.json, or tasks.db / tasks.jsonl next to it
+	// with "storage: db" / "storage: log"
+	var repo task.Repository
+	switch cfg.Storage {
+	case config.StorageDB:
+		dbStorage := storage.NewDBStorage(storage.DBPath(path))
+		dbStorage.SetLockTimeout(timeout)
+		repo = dbStorage
+	case config.StorageLog:
+		logStorage := storage.NewEventLogStorage(storage.EventLogPath(path))
+		logStorage.SetLockTimeout(timeout)
+		repo = logStorage
+	default:
+		fileStorage := storage.NewJSONStorage(path)
+		fileStorage.SetLockTimeout(timeout)
+		repo = fileStorage
+	}
 
 	// Initialize task manager
-	taskManager, err := task.NewTaskManager(fileStorage)
+	taskManager, err := task.NewTaskManager(repo)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing task manager: %v\n", err)
 		os.Exit(1)
 	}
 
+	// Record every change next to the tasks file so it can be undone
+	taskManager.SetJournal(storage.NewJSONJournal(storage.SiblingPath(path, "journal")))
+
+	// Completed tasks can be moved out of the main file into the archive
+	taskManager.SetArchive(storage.NewJSONStorage(storage.SiblingPath(path, "archive")))
+
+	// Keep a field-level history of every task for `tm log`, noting who made each change
+	taskManager.SetChangeLog(storage.NewJSONChangeLog(storage.ChangeLogPath(path)))
+	if u, err := user.Current(); err == nil {
+		taskManager.SetActor(u.Username)
+	}
+
 	// Parse and execute command
-	if err := cli.ExecuteCommand(taskManager, os.Args[1:]); err != nil {
+	if err := cli.ExecuteCommand(taskManager, args); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
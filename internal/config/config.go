@@ -0,0 +1,100 @@
+// Package config reads the optional tm configuration file.
+//
+// The file holds one "key: value" setting per line; blank lines and lines
+// starting with "#" are ignored. For example:
+//
+//	# Keep tasks in the embedded database instead of tasks.json
+//	storage: db
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnvFile is the environment variable that overrides the default config file location.
+const EnvFile = "TM_CONFIG"
+
+// Storage backends.
+const (
+	StorageJSON = "json"
+	StorageDB   = "db"
+	StorageLog  = "log"
+)
+
+// Config holds the user's settings.
+type Config struct {
+	// Storage selects the task storage backend: StorageJSON, StorageDB or StorageLog.
+	Storage string
+}
+
+// Default returns the settings used when there is no config file.
+func Default() Config {
+	return Config{Storage: StorageJSON}
+}
+
+// ResolvePath determines which config file to read: the TM_CONFIG environment
+// variable, or $XDG_CONFIG_HOME/tm/config.yaml (~/.config/tm/config.yaml).
+func ResolvePath() (string, error) {
+	if env := os.Getenv(EnvFile); env != "" {
+		return env, nil
+	}
+
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not determine home directory: %w", err)
+		}
+		configDir = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(configDir, "tm", "config.yaml"), nil
+}
+
+// Load reads the config file at path. A missing file yields the defaults.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read config: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(text, ":")
+		if !ok {
+			return cfg, fmt.Errorf("%s:%d: expected \"key: value\"", path, line)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+
+		switch key {
+		case "storage":
+			if value != StorageJSON && value != StorageDB && value != StorageLog {
+				return cfg, fmt.Errorf("%s:%d: invalid storage %q (use %s, %s or %s)", path, line, value, StorageJSON, StorageDB, StorageLog)
+			}
+			cfg.Storage = value
+		default:
+			return cfg, fmt.Errorf("%s:%d: unknown setting %q", path, line, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return cfg, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLoad tests reading settings from a config file
+func TestLoad(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write fixture: %v", err)
+		}
+		return path
+	}
+
+	t.Run("Uses defaults without a file", func(t *testing.T) {
+		cfg, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if cfg.Storage != StorageJSON {
+			t.Errorf("Expected json storage, got %q", cfg.Storage)
+		}
+	})
+
+	t.Run("Reads the storage backend", func(t *testing.T) {
+		cfg, err := Load(write(t, "# settings\n\nstorage: db\n"))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if cfg.Storage != StorageDB {
+			t.Errorf("Expected db storage, got %q", cfg.Storage)
+		}
+	})
+
+	t.Run("Reads the event log backend", func(t *testing.T) {
+		cfg, err := Load(write(t, "storage: \"log\"\n"))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if cfg.Storage != StorageLog {
+			t.Errorf("Expected log storage, got %q", cfg.Storage)
+		}
+	})
+
+	t.Run("Rejects invalid settings", func(t *testing.T) {
+		for _, content := range []string{"storage: sqlite\n", "colour: on\n", "storage db\n"} {
+			if _, err := Load(write(t, content)); err == nil {
+				t.Errorf("Expected error for %q", content)
+			}
+		}
+	})
+}
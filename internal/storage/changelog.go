@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/amit9838/taskmanager/internal/task"
+)
+
+// JSONChangeLog stores the per-task change history as JSON lines, one change per
+// line, appended next to the tasks file. The file only ever grows, so recording a
+// change does not rewrite what came before.
+type JSONChangeLog struct {
+	filename string
+}
+
+// NewJSONChangeLog creates a change log stored in the given file.
+// A missing file is treated as an empty log.
+func NewJSONChangeLog(filename string) *JSONChangeLog {
+	return &JSONChangeLog{filename: filename}
+}
+
+// ChangeLogPath derives the change log location from the tasks file path,
+// e.g. "tasks.json" becomes "tasks.history.jsonl".
+func ChangeLogPath(tasksPath string) string {
+	return strings.TrimSuffix(tasksPath, filepath.Ext(tasksPath)) + ".history.jsonl"
+}
+
+// AppendChanges adds changes to the end of the log and syncs them to disk.
+func (l *JSONChangeLog) AppendChanges(changes []task.Change) error {
+	var buf bytes.Buffer
+	for _, c := range changes {
+		data, err := json.Marshal(c)
+		if err != nil {
+			return fmt.Errorf("failed to encode change: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.filename), 0755); err != nil {
+		return fmt.Errorf("could not create data directory: %w", err)
+	}
+	f, err := os.OpenFile(l.filename, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open change log: %w", err)
+	}
+	defer f.Close()
+
+	data := buf.Bytes()
+
+	// Start on a fresh line if the last write was cut short
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			data = append([]byte{'\n'}, data...)
+		}
+	}
+
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write change log: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to write change log: %w", err)
+	}
+	return nil
+}
+
+// LoadChanges returns the changes recorded for the task with the given ID,
+// oldest first. Lines that cannot be decoded, such as one cut short by a
+// crash, are skipped.
+func (l *JSONChangeLog) LoadChanges(id int) ([]task.Change, error) {
+	f, err := os.Open(l.filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read change log: %w", err)
+	}
+	defer f.Close()
+
+	var changes []task.Change
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var c task.Change
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			continue
+		}
+		if c.TaskID == id {
+			changes = append(changes, c)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read change log: %w", err)
+	}
+
+	return changes, nil
+}
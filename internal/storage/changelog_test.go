@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amit9838/taskmanager/internal/task"
+)
+
+// TestJSONChangeLog tests appending changes and reading them back per task
+func TestJSONChangeLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "tasks.history.jsonl")
+	l := NewJSONChangeLog(path)
+
+	changes, err := l.LoadChanges(1)
+	if err != nil || len(changes) != 0 {
+		t.Fatalf("Expected an empty log, got %v (%v)", changes, err)
+	}
+
+	now := time.Now()
+	if err := l.AppendChanges([]task.Change{
+		{TaskID: 1, Time: now, Op: "add", Kind: task.ChangeCreated},
+		{TaskID: 2, Time: now, Op: "add", Kind: task.ChangeCreated},
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Simulate a write cut short by a crash
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	f.WriteString(`{"task_id":1,"ti`)
+	f.Close()
+
+	if err := l.AppendChanges([]task.Change{
+		{TaskID: 1, Time: now, Op: "done", Kind: task.ChangeCompleted, Fields: []task.FieldChange{{Field: "status", Old: "pending", New: "done"}}},
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	changes, err = NewJSONChangeLog(path).LoadChanges(1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(changes) != 2 || changes[1].Kind != task.ChangeCompleted || changes[1].Fields[0].New != "done" {
+		t.Errorf("Expected the created and completed changes of task 1, got %+v", changes)
+	}
+}
@@ -0,0 +1,409 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amit9838/taskmanager/internal/task"
+)
+
+// Key layout of the database. Index keys have empty values; the task ID at the
+// end of each key points at the "task/" record.
+const (
+	keyDBID         = "meta/id"
+	keyDBRevision   = "meta/revision"
+	keyDBLastID     = "meta/last_id"
+	keyDBSchema     = "meta/schema_version"
+	taskKeyPrefix   = "task/"
+	statusKeyPrefix = "idx/status/"
+	tagKeyPrefix    = "idx/tag/"
+	dueKeyPrefix    = "idx/due/"
+)
+
+// dueKeyLayout sorts lexically in time order, which due index scans rely on.
+const dueKeyLayout = "20060102150405.000000000"
+
+// DBStorage stores tasks in an embedded single-file database. Unlike JSONStorage,
+// saving only appends the tasks that changed, and lookups by status, due date and
+// tag go through indexes kept in the same file.
+//
+// Tasks written with an older schema version are migrated as they are read, and
+// stored in the current format by the next Save; databases from a newer version
+// are refused.
+type DBStorage struct {
+	filename    string
+	lockTimeout time.Duration
+	log         *kvLog
+}
+
+// NewDBStorage creates a DBStorage backed by the given file. The file is created,
+// along with any missing parent directories, the first time it is used.
+func NewDBStorage(filename string) *DBStorage {
+	return &DBStorage{filename: filename, lockTimeout: DefaultLockTimeout}
+}
+
+// DBPath derives the database location from the tasks file path,
+// e.g. "tasks.json" becomes "tasks.db".
+func DBPath(tasksPath string) string {
+	return strings.TrimSuffix(tasksPath, filepath.Ext(tasksPath)) + ".db"
+}
+
+// JSONPath is the inverse of DBPath, e.g. "tasks.db" becomes "tasks.json".
+func JSONPath(dbPath string) string {
+	return strings.TrimSuffix(dbPath, filepath.Ext(dbPath)) + ".json"
+}
+
+// Path returns the location of the database file.
+func (s *DBStorage) Path() string {
+	return s.filename
+}
+
+// SetLockTimeout sets how long Lock waits before giving up.
+func (s *DBStorage) SetLockTimeout(timeout time.Duration) {
+	s.lockTimeout = timeout
+}
+
+// Lock takes an exclusive advisory lock on the database for a load-modify-save cycle.
+func (s *DBStorage) Lock() (func(), error) {
+	return lockFile(s.filename, s.lockTimeout)
+}
+
+// Load returns every task in ID order together with the current revision.
+func (s *DBStorage) Load() ([]task.Task, task.Revision, error) {
+	if err := s.open(); err != nil {
+		return nil, "", err
+	}
+
+	tasks := []task.Task{}
+	for _, key := range s.log.keys(taskKeyPrefix) {
+		t, err := s.decode(key)
+		if err != nil {
+			return nil, "", err
+		}
+		tasks = append(tasks, t)
+	}
+
+	return tasks, s.revision(), nil
+}
+
+// Save writes the tasks that differ from the stored ones, removes the stored
+// tasks missing from the list, and returns the new revision. It is rejected with
+// task.ErrConflict if the database is no longer at the base revision.
+func (s *DBStorage) Save(tasks []task.Task, base task.Revision) (task.Revision, error) {
+	if err := s.open(); err != nil {
+		return "", err
+	}
+	if current := s.revision(); current != base {
+		return "", fmt.Errorf("%w: %s was modified since it was loaded", task.ErrConflict, s.filename)
+	}
+
+	var ops []kvOp
+	kept := map[string]bool{}
+	for _, t := range tasks {
+		key := taskKey(t.ID)
+		kept[key] = true
+
+		value, err := json.Marshal(t)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode task %d: %w", t.ID, err)
+		}
+
+		old, exists := s.log.get(key)
+		if exists && bytes.Equal(old, value) {
+			continue
+		}
+
+		var oldKeys []string
+		if exists {
+			oldTask, err := s.decode(key)
+			if err != nil {
+				return "", err
+			}
+			oldKeys = indexKeys(oldTask)
+		}
+		ops = append(ops, kvOp{key: key, value: value})
+		ops = append(ops, reindex(oldKeys, indexKeys(t))...)
+	}
+
+	for _, key := range s.log.keys(taskKeyPrefix) {
+		if kept[key] {
+			continue
+		}
+		oldTask, err := s.decode(key)
+		if err != nil {
+			return "", err
+		}
+		ops = append(ops, kvOp{key: key})
+		ops = append(ops, reindex(indexKeys(oldTask), nil)...)
+	}
+
+	// Every task is now written in the current format
+	if version := []byte(strconv.Itoa(CurrentSchemaVersion)); !bytes.Equal(s.log.data[keyDBSchema], version) {
+		ops = append(ops, kvOp{key: keyDBSchema, value: version})
+	}
+	if len(ops) == 0 {
+		return base, nil
+	}
+
+	last, err := s.LastID()
+	if err != nil {
+		return "", err
+	}
+	if highest := highestID(tasks); highest > last {
+		ops = append(ops, kvOp{key: keyDBLastID, value: []byte(strconv.Itoa(highest))})
+	}
+
+	ops, err = s.bumpRevision(ops)
+	if err != nil {
+		return "", err
+	}
+	if err := s.log.write(ops); err != nil {
+		return "", err
+	}
+
+	return s.revision(), nil
+}
+
+// LastID returns the highest task ID ever saved to the database, including the
+// IDs of tasks that have since been purged.
+func (s *DBStorage) LastID() (int, error) {
+	if err := s.open(); err != nil {
+		return 0, err
+	}
+
+	value, _ := s.log.get(keyDBLastID)
+	last, _ := strconv.Atoi(string(value))
+	// Databases written before the last ID was kept only know their tasks
+	if keys := s.log.keys(taskKeyPrefix); len(keys) > 0 {
+		last = max(last, keyID(keys[len(keys)-1]))
+	}
+	return last, nil
+}
+
+// ReserveID raises the highest task ID kept in the database to id.
+// Hold the Lock while calling it.
+func (s *DBStorage) ReserveID(id int) error {
+	last, err := s.LastID()
+	if err != nil || id <= last {
+		return err
+	}
+
+	ops, err := s.bumpRevision([]kvOp{{key: keyDBLastID, value: []byte(strconv.Itoa(id))}})
+	if err != nil {
+		return err
+	}
+	return s.log.write(ops)
+}
+
+// Query returns the tasks matching q, in ID order, reading only the tasks that
+// the status, due date and tag indexes select.
+func (s *DBStorage) Query(q task.Query) ([]task.Task, error) {
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	var candidates map[int]bool
+	narrow := func(ids map[int]bool) {
+		if candidates == nil {
+			candidates = ids
+			return
+		}
+		for id := range candidates {
+			if !ids[id] {
+				delete(candidates, id)
+			}
+		}
+	}
+
+	if len(q.Statuses) > 0 {
+		ids := map[int]bool{}
+		for _, status := range q.Statuses {
+			s.scanIDs(statusKeyPrefix+string(status)+"/", ids)
+		}
+		narrow(ids)
+	}
+
+	for _, tag := range q.Tags {
+		ids := map[int]bool{}
+		s.scanIDs(tagKeyPrefix+tag+"/", ids)
+		narrow(ids)
+	}
+
+	if q.DueBefore != nil {
+		cutoff := dueKeyPrefix + q.DueBefore.UTC().Format(dueKeyLayout)
+		ids := map[int]bool{}
+		for _, key := range s.log.keys(dueKeyPrefix) {
+			if key >= cutoff {
+				break
+			}
+			ids[keyID(key)] = true
+		}
+		narrow(ids)
+	}
+
+	if candidates == nil {
+		tasks, _, err := s.Load()
+		return tasks, err
+	}
+
+	ids := make([]int, 0, len(candidates))
+	for id := range candidates {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	tasks := make([]task.Task, 0, len(ids))
+	for _, id := range ids {
+		t, err := s.decode(taskKey(id))
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, nil
+}
+
+// open opens the database on first use and otherwise picks up changes made
+// by other processes. It refuses databases written by a newer version, which
+// may contain data this version would silently drop.
+func (s *DBStorage) open() error {
+	if s.log != nil {
+		if err := s.log.refresh(); err != nil {
+			return err
+		}
+	} else {
+		log, err := openKVLog(s.filename)
+		if err != nil {
+			return err
+		}
+		s.log = log
+	}
+
+	if version := s.schemaVersion(); version > CurrentSchemaVersion {
+		return fmt.Errorf("database uses schema version %d, but this version of tm only supports up to %d; please upgrade tm", version, CurrentSchemaVersion)
+	}
+	return nil
+}
+
+// schemaVersion returns the schema version the stored tasks were written with.
+// Databases from before it was stored hold version 1 tasks.
+func (s *DBStorage) schemaVersion() int {
+	value, ok := s.log.get(keyDBSchema)
+	if !ok {
+		if len(s.log.keys(taskKeyPrefix)) == 0 {
+			return CurrentSchemaVersion
+		}
+		return 1
+	}
+	version, _ := strconv.Atoi(string(value))
+	return version
+}
+
+// revision combines the database's random ID with its commit counter, so a
+// database that is deleted and recreated never repeats an earlier revision.
+// A database that has never been written to has an empty revision.
+func (s *DBStorage) revision() task.Revision {
+	id, ok := s.log.get(keyDBID)
+	if !ok {
+		return ""
+	}
+	counter, _ := s.log.get(keyDBRevision)
+	return task.Revision(string(id) + "." + string(counter))
+}
+
+// bumpRevision adds the revision update to a batch, assigning the database
+// its ID on the first write.
+func (s *DBStorage) bumpRevision(ops []kvOp) ([]kvOp, error) {
+	if _, ok := s.log.get(keyDBID); !ok {
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			return nil, fmt.Errorf("failed to create database ID: %w", err)
+		}
+		ops = append(ops, kvOp{key: keyDBID, value: []byte(hex.EncodeToString(id))})
+	}
+
+	value, _ := s.log.get(keyDBRevision)
+	counter, _ := strconv.Atoi(string(value))
+	return append(ops, kvOp{key: keyDBRevision, value: []byte(strconv.Itoa(counter + 1))}), nil
+}
+
+// decode reads the task stored under key, migrating it to the current schema version.
+func (s *DBStorage) decode(key string) (task.Task, error) {
+	value, _ := s.log.get(key)
+	value, err := migrateTask(value, s.schemaVersion())
+	if err != nil {
+		return task.Task{}, fmt.Errorf("failed to migrate %s: %w", key, err)
+	}
+
+	var t task.Task
+	if err := json.Unmarshal(value, &t); err != nil {
+		return task.Task{}, fmt.Errorf("failed to decode %s: %w", key, err)
+	}
+	return t, nil
+}
+
+// scanIDs adds the task IDs of the index keys directly under prefix to ids.
+// Keys with further segments belong to longer values, e.g. tag "a/b" under "a".
+func (s *DBStorage) scanIDs(prefix string, ids map[int]bool) {
+	for _, key := range s.log.keys(prefix) {
+		if !strings.Contains(key[len(prefix):], "/") {
+			ids[keyID(key)] = true
+		}
+	}
+}
+
+func taskKey(id int) string {
+	return fmt.Sprintf("%s%010d", taskKeyPrefix, id)
+}
+
+// keyID extracts the task ID from the last segment of a task or index key.
+func keyID(key string) int {
+	id, _ := strconv.Atoi(key[strings.LastIndex(key, "/")+1:])
+	return id
+}
+
+// indexKeys returns the index entries for t.
+func indexKeys(t task.Task) []string {
+	id := fmt.Sprintf("/%010d", t.ID)
+	keys := []string{statusKeyPrefix + string(t.Status) + id}
+	for _, tag := range t.Tags {
+		keys = append(keys, tagKeyPrefix+tag+id)
+	}
+	if t.DueAt != nil {
+		keys = append(keys, dueKeyPrefix+t.DueAt.UTC().Format(dueKeyLayout)+id)
+	}
+	return keys
+}
+
+// reindex returns the operations that replace the index entries old with new.
+func reindex(old, new []string) []kvOp {
+	var ops []kvOp
+	for _, key := range old {
+		if !containsKey(new, key) {
+			ops = append(ops, kvOp{key: key})
+		}
+	}
+	for _, key := range new {
+		if !containsKey(old, key) {
+			ops = append(ops, kvOp{key: key, value: []byte{}})
+		}
+	}
+	return ops
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
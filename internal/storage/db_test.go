@@ -0,0 +1,281 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amit9838/taskmanager/internal/task"
+)
+
+func newDBTasks(n int) []task.Task {
+	tasks := make([]task.Task, n)
+	for i := range tasks {
+		tasks[i] = task.Task{ID: i + 1, Description: fmt.Sprintf("Task %d", i+1), Status: task.StatusPending}
+	}
+	return tasks
+}
+
+// TestDBSaveAndLoad tests a round trip through the database, including from a second handle
+func TestDBSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "tasks.db")
+	db := NewDBStorage(path)
+
+	tasks, rev, err := db.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(tasks) != 0 || rev != "" {
+		t.Fatalf("Expected an empty database, got %d tasks at %q", len(tasks), rev)
+	}
+
+	if _, err := db.Save(newDBTasks(3), rev); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	loaded, _, err := NewDBStorage(path).Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(loaded) != 3 || loaded[2].Description != "Task 3" {
+		t.Errorf("Tasks not round-tripped correctly: %+v", loaded)
+	}
+}
+
+// TestDBSaveAppendsChanges tests that saving writes only the tasks that changed
+func TestDBSaveAppendsChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	db := NewDBStorage(path)
+
+	_, rev, _ := db.Load()
+	rev, err := db.Save(newDBTasks(100), rev)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	before, _ := os.Stat(path)
+
+	tasks, rev, _ := db.Load()
+	tasks[50].Description = "Changed"
+	tasks = tasks[:99]
+	if _, err := db.Save(tasks, rev); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	after, _ := os.Stat(path)
+
+	if grown := after.Size() - before.Size(); grown <= 0 || grown > before.Size()/10 {
+		t.Errorf("Expected a small append, file grew by %d bytes from %d", grown, before.Size())
+	}
+
+	loaded, _, err := NewDBStorage(path).Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(loaded) != 99 || loaded[50].Description != "Changed" {
+		t.Errorf("Expected 99 tasks with task 51 changed, got %d", len(loaded))
+	}
+}
+
+// TestDBRejectsStaleRevision tests that a second handle's write makes the first one's save conflict
+func TestDBRejectsStaleRevision(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	first := NewDBStorage(path)
+	second := NewDBStorage(path)
+
+	tasks, rev, _ := first.Load()
+	_, otherRev, _ := second.Load()
+	if _, err := second.Save(newDBTasks(1), otherRev); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := first.Save(tasks, rev); !errors.Is(err, task.ErrConflict) {
+		t.Fatalf("Expected conflict, got %v", err)
+	}
+
+	loaded, _, err := first.Load()
+	if err != nil || len(loaded) != 1 {
+		t.Errorf("Expected the other write to be visible, got %d tasks (%v)", len(loaded), err)
+	}
+}
+
+// TestDBIgnoresTornWrite tests that a batch cut short by a crash is discarded
+func TestDBIgnoresTornWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	db := NewDBStorage(path)
+
+	_, rev, _ := db.Load()
+	rev, _ = db.Save(newDBTasks(2), rev)
+	intact, _ := os.Stat(path)
+
+	tasks, _, _ := db.Load()
+	tasks[0].Description = "Lost"
+	db.Save(tasks, rev)
+
+	// Cut the last batch in half
+	latest, _ := os.Stat(path)
+	if err := os.Truncate(path, intact.Size()+(latest.Size()-intact.Size())/2); err != nil {
+		t.Fatalf("Failed to truncate: %v", err)
+	}
+
+	reopened := NewDBStorage(path)
+	loaded, rev, err := reopened.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(loaded) != 2 || loaded[0].Description != "Task 1" {
+		t.Fatalf("Expected the state before the torn batch, got %+v", loaded)
+	}
+
+	loaded[1].Description = "Written after recovery"
+	if _, err := reopened.Save(loaded, rev); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if again, _, _ := NewDBStorage(path).Load(); len(again) != 2 || again[1].Description != "Written after recovery" {
+		t.Errorf("Expected the new write after the recovered state, got %+v", again)
+	}
+}
+
+// TestDBMigratesOlderSchema tests that tasks from a database without a schema
+// version are migrated on load and stored in the current format on save
+func TestDBMigratesOlderSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	log, err := openKVLog(path)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	done := `{"id": 1, "description": "Old", "status": "done", "created_at": "2026-01-01T00:00:00Z", "updated_at": "2026-01-02T00:00:00Z"}`
+	log.write([]kvOp{
+		{key: taskKey(1), value: []byte(done)},
+		{key: keyDBID, value: []byte("0123456789abcdef")},
+		{key: keyDBRevision, value: []byte("1")},
+	})
+
+	db := NewDBStorage(path)
+	tasks, rev, err := db.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].CompletedAt == nil || !tasks[0].CompletedAt.Equal(tasks[0].UpdatedAt) {
+		t.Fatalf("Expected completed_at backfilled from updated_at, got %+v", tasks)
+	}
+
+	if _, err := db.Save(tasks, rev); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	reopened := NewDBStorage(path)
+	reopened.open()
+	if version := reopened.schemaVersion(); version != CurrentSchemaVersion {
+		t.Errorf("Expected schema version %d after saving, got %d", CurrentSchemaVersion, version)
+	}
+}
+
+// TestDBRefusesNewerSchema tests that a database from a newer version is not read
+func TestDBRefusesNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	db := NewDBStorage(path)
+	_, rev, _ := db.Load()
+	db.Save(newDBTasks(1), rev)
+	db.log.write([]kvOp{{key: keyDBSchema, value: []byte("99")}})
+
+	if _, _, err := NewDBStorage(path).Load(); err == nil {
+		t.Fatal("Expected error loading a newer schema version")
+	}
+}
+
+// TestDBQuery tests lookups through the status, due date and tag indexes
+func TestDBQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	db := NewDBStorage(path)
+
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	yesterday, tomorrow := now.AddDate(0, 0, -1), now.AddDate(0, 0, 1)
+	tasks := newDBTasks(4)
+	tasks[0].Tags = []string{"home"}
+	tasks[0].DueAt = &yesterday
+	tasks[1].Tags = []string{"home", "errand"}
+	tasks[1].DueAt = &tomorrow
+	tasks[2].Status = task.StatusDone
+	tasks[2].Tags = []string{"home/garden"}
+	tasks[3].Status = task.StatusInProgress
+
+	_, rev, _ := db.Load()
+	rev, err := db.Save(tasks, rev)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ids := func(q task.Query) []int {
+		found, err := db.Query(q)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		var ids []int
+		for _, t := range found {
+			ids = append(ids, t.ID)
+		}
+		return ids
+	}
+
+	cases := []struct {
+		name  string
+		query task.Query
+		want  string
+	}{
+		{"Status", task.Query{Statuses: []task.TaskStatus{task.StatusPending, task.StatusInProgress}}, "[1 2 4]"},
+		{"Tag", task.Query{Tags: []string{"home"}}, "[1 2]"},
+		{"All tags", task.Query{Tags: []string{"home", "errand"}}, "[2]"},
+		{"Due before", task.Query{DueBefore: &now}, "[1]"},
+		{"Combined", task.Query{Tags: []string{"home"}, Statuses: []task.TaskStatus{task.StatusPending}, DueBefore: &tomorrow}, "[1]"},
+		{"Everything", task.Query{}, "[1 2 3 4]"},
+	}
+	for _, c := range cases {
+		if got := fmt.Sprint(ids(c.query)); got != c.want {
+			t.Errorf("%s: expected %s, got %s", c.name, c.want, got)
+		}
+	}
+
+	// Indexes follow changes to the tasks
+	tasks[0].Tags = nil
+	tasks[0].Status = task.StatusDone
+	if _, err := db.Save(tasks, rev); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := fmt.Sprint(ids(task.Query{Tags: []string{"home"}, Statuses: []task.TaskStatus{task.StatusPending}})); got != "[2]" {
+		t.Errorf("Expected [2] after reindexing, got %s", got)
+	}
+}
+
+// TestDBCompaction tests that rewriting the log keeps every current value
+func TestDBCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	db := NewDBStorage(path)
+
+	_, rev, _ := db.Load()
+	for i := 0; i < 5; i++ {
+		tasks := newDBTasks(10)
+		tasks[0].Description = fmt.Sprintf("Version %d", i)
+		var err error
+		if rev, err = db.Save(tasks, rev); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	before, _ := os.Stat(path)
+
+	if err := db.log.compact(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Errorf("Expected compaction to shrink the file, got %d from %d bytes", after.Size(), before.Size())
+	}
+
+	loaded, loadedRev, err := NewDBStorage(path).Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(loaded) != 10 || loaded[0].Description != "Version 4" || loadedRev != rev {
+		t.Errorf("Expected the latest state at revision %s, got %q at %s", rev, loaded[0].Description, loadedRev)
+	}
+}
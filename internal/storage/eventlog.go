@@ -0,0 +1,483 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amit9838/taskmanager/internal/task"
+)
+
+// eventType names what a line of the event log records.
+type eventType string
+
+const (
+	eventSnapshot  eventType = "snapshot"
+	eventCreated   eventType = "created"
+	eventUpdated   eventType = "updated"
+	eventCompleted eventType = "completed"
+	eventDeleted   eventType = "deleted"
+)
+
+// defaultCompactAfter is how many events may follow the snapshot before the log
+// is compacted into a new snapshot.
+const defaultCompactAfter = 1000
+
+// logEvent is one line of the event log. Every log starts with a snapshot holding
+// the complete state at that point; the events after it each carry the full task
+// they created, updated or completed, or the ID of the task they deleted.
+type logEvent struct {
+	Seq  int64     `json:"seq"`
+	Type eventType `json:"type"`
+	At   time.Time `json:"at"`
+	ID   int       `json:"id,omitempty"`
+	// Task is the task after the event.
+	Task json.RawMessage `json:"task,omitempty"`
+	// Commit marks the last event written by a Save. Events without a commit
+	// after them belong to an interrupted write and are ignored.
+	Commit bool `json:"commit,omitempty"`
+	// SchemaVersion is the version the task or tasks of the event were written
+	// with. Events from before it was recorded have the version of the snapshot.
+	SchemaVersion int `json:"schema_version,omitempty"`
+
+	// Snapshot fields
+	LogID string `json:"log_id,omitempty"`
+	// LastID is the highest task ID saved before the snapshot, including
+	// tasks that were deleted since.
+	LastID int               `json:"last_id,omitempty"`
+	Tasks  []json.RawMessage `json:"tasks,omitempty"`
+}
+
+// EventLogStorage stores tasks as an append-only log of JSON lines, one event per
+// change, and rebuilds the task list by replaying it. Saving appends only the
+// events for tasks that changed, and a crash mid-write loses at most that write.
+// Once enough events pile up the log is replaced by a single snapshot.
+//
+// Tasks written with an older schema version are migrated as they are replayed;
+// logs with events from a newer version are refused.
+type EventLogStorage struct {
+	filename     string
+	lockTimeout  time.Duration
+	compactAfter int
+
+	// State replayed from the file, kept between calls so that only new
+	// lines need reading.
+	logID string
+	seq   int64
+	// version is the schema version of the snapshot.
+	version int
+	tasks   map[int]json.RawMessage
+	lastID  int
+	events  int
+	// size is the offset just past the last committed event.
+	size int64
+	// info identifies the file that was read, to notice when it is replaced.
+	info os.FileInfo
+}
+
+// NewEventLogStorage creates an EventLogStorage backed by the given file. The file
+// is created, along with any missing parent directories, on the first save.
+func NewEventLogStorage(filename string) *EventLogStorage {
+	return &EventLogStorage{
+		filename:     filename,
+		lockTimeout:  DefaultLockTimeout,
+		compactAfter: defaultCompactAfter,
+		tasks:        map[int]json.RawMessage{},
+	}
+}
+
+// EventLogPath derives the event log location from the tasks file path,
+// e.g. "tasks.json" becomes "tasks.jsonl".
+func EventLogPath(tasksPath string) string {
+	return strings.TrimSuffix(tasksPath, filepath.Ext(tasksPath)) + ".jsonl"
+}
+
+// Path returns the location of the event log.
+func (s *EventLogStorage) Path() string {
+	return s.filename
+}
+
+// SetLockTimeout sets how long Lock waits before giving up.
+func (s *EventLogStorage) SetLockTimeout(timeout time.Duration) {
+	s.lockTimeout = timeout
+}
+
+// Lock takes an exclusive advisory lock on the log for a load-modify-save cycle.
+func (s *EventLogStorage) Lock() (func(), error) {
+	return lockFile(s.filename, s.lockTimeout)
+}
+
+// Load replays the log and returns every task in ID order together with the
+// current revision.
+func (s *EventLogStorage) Load() ([]task.Task, task.Revision, error) {
+	if err := s.refresh(); err != nil {
+		return nil, "", err
+	}
+
+	ids := s.ids()
+	tasks := make([]task.Task, 0, len(ids))
+	for _, id := range ids {
+		var t task.Task
+		if err := json.Unmarshal(s.tasks[id], &t); err != nil {
+			return nil, "", fmt.Errorf("failed to decode task %d: %w", id, err)
+		}
+		tasks = append(tasks, t)
+	}
+
+	return tasks, s.revision(), nil
+}
+
+// Save appends an event for every task that was added, changed or removed and
+// returns the new revision. It is rejected with task.ErrConflict if the log is
+// no longer at the base revision.
+func (s *EventLogStorage) Save(tasks []task.Task, base task.Revision) (task.Revision, error) {
+	if err := s.refresh(); err != nil {
+		return "", err
+	}
+	if current := s.revision(); current != base {
+		return "", fmt.Errorf("%w: %s was modified since it was loaded", task.ErrConflict, s.filename)
+	}
+
+	now := time.Now().UTC()
+	var events []logEvent
+	kept := map[int]bool{}
+	for _, t := range tasks {
+		kept[t.ID] = true
+
+		value, err := json.Marshal(t)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode task %d: %w", t.ID, err)
+		}
+
+		old, exists := s.tasks[t.ID]
+		if exists && bytes.Equal(old, value) {
+			continue
+		}
+
+		e := logEvent{Type: eventCreated, At: now, ID: t.ID, Task: value}
+		if exists {
+			var before task.Task
+			if err := json.Unmarshal(old, &before); err != nil {
+				return "", fmt.Errorf("failed to decode task %d: %w", t.ID, err)
+			}
+			e.Type = eventUpdated
+			if t.IsDone() && !before.IsDone() {
+				e.Type = eventCompleted
+			}
+		}
+		events = append(events, e)
+	}
+
+	for _, id := range s.ids() {
+		if !kept[id] {
+			events = append(events, logEvent{Type: eventDeleted, At: now, ID: id})
+		}
+	}
+
+	if len(events) == 0 {
+		return base, nil
+	}
+
+	if err := s.append(events); err != nil {
+		return "", err
+	}
+	if s.events >= s.compactAfter {
+		if err := s.compact(); err != nil {
+			return "", err
+		}
+	}
+
+	return s.revision(), nil
+}
+
+// LastID returns the highest task ID ever saved to the log, including the IDs
+// of tasks that have since been purged.
+func (s *EventLogStorage) LastID() (int, error) {
+	if err := s.refresh(); err != nil {
+		return 0, err
+	}
+	return s.lastID, nil
+}
+
+// ReserveID raises the highest task ID kept in the log to id by writing a new
+// snapshot. Hold the Lock while calling it.
+func (s *EventLogStorage) ReserveID(id int) error {
+	if err := s.refresh(); err != nil {
+		return err
+	}
+	if id <= s.lastID {
+		return nil
+	}
+
+	s.lastID = id
+	if s.logID == "" {
+		return s.append(nil)
+	}
+	return s.compact()
+}
+
+// revision combines the log's random ID with the sequence number of its last
+// event, so a log that is deleted and recreated never repeats an earlier
+// revision. A log that does not exist yet has an empty revision.
+func (s *EventLogStorage) revision() task.Revision {
+	if s.logID == "" {
+		return ""
+	}
+	return task.Revision(s.logID + "." + strconv.FormatInt(s.seq, 10))
+}
+
+// ids returns the IDs of the current tasks in order.
+func (s *EventLogStorage) ids() []int {
+	ids := make([]int, 0, len(s.tasks))
+	for id := range s.tasks {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// refresh picks up events written by other processes since the log was last read.
+func (s *EventLogStorage) refresh() error {
+	info, err := os.Stat(s.filename)
+	if os.IsNotExist(err) {
+		s.reset()
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read event log: %w", err)
+	}
+
+	switch {
+	case s.info == nil || !os.SameFile(info, s.info) || info.Size() < s.size:
+		// New, or replaced by a compaction in another process
+		s.reset()
+		return s.readFrom(0)
+	case info.Size() > s.size:
+		return s.readFrom(s.size)
+	}
+	return nil
+}
+
+// reset forgets the replayed state.
+func (s *EventLogStorage) reset() {
+	s.logID, s.seq, s.version, s.lastID, s.events, s.size, s.info = "", 0, 0, 0, 0, 0, nil
+	s.tasks = map[int]json.RawMessage{}
+}
+
+// readFrom replays the committed events after offset. An incomplete last line or
+// events without a commit after them, such as those of a write torn by a crash,
+// are ignored.
+func (s *EventLogStorage) readFrom(offset int64) error {
+	f, err := os.Open(s.filename)
+	if err != nil {
+		return fmt.Errorf("failed to read event log: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read event log: %w", err)
+	}
+
+	data := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(data, offset); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read event log: %w", err)
+	}
+
+	var pending []logEvent
+	for pos := 0; pos < len(data); {
+		end := bytes.IndexByte(data[pos:], '\n')
+		if end < 0 {
+			break
+		}
+
+		var e logEvent
+		if err := json.Unmarshal(data[pos:pos+end], &e); err != nil {
+			if pos+end+1 < len(data) {
+				return fmt.Errorf("%s is corrupt at byte %d: %w", s.filename, offset+int64(pos), err)
+			}
+			break
+		}
+		if offset == 0 && pos == 0 && e.Type != eventSnapshot {
+			return fmt.Errorf("%s is not a tm event log", s.filename)
+		}
+		pos += end + 1
+
+		pending = append(pending, e)
+		if e.Commit {
+			for _, e := range pending {
+				if err := s.apply(e); err != nil {
+					return err
+				}
+			}
+			pending = nil
+			s.size = offset + int64(pos)
+		}
+	}
+
+	s.info = info
+	return nil
+}
+
+// apply updates the replayed state with one committed event, migrating the
+// tasks it carries to the current schema version.
+func (s *EventLogStorage) apply(e logEvent) error {
+	version := e.SchemaVersion
+	if version == 0 {
+		version = s.version
+	}
+	if version > CurrentSchemaVersion {
+		return fmt.Errorf("event log uses schema version %d, but this version of tm only supports up to %d; please upgrade tm", version, CurrentSchemaVersion)
+	}
+
+	switch e.Type {
+	case eventSnapshot:
+		s.tasks = map[int]json.RawMessage{}
+		for _, raw := range e.Tasks {
+			raw, err := migrateTask(raw, max(version, 1))
+			if err != nil {
+				return fmt.Errorf("failed to migrate snapshot: %w", err)
+			}
+			var t struct {
+				ID int `json:"id"`
+			}
+			if err := json.Unmarshal(raw, &t); err != nil {
+				return fmt.Errorf("failed to decode snapshot: %w", err)
+			}
+			s.tasks[t.ID] = raw
+			s.lastID = max(s.lastID, t.ID)
+		}
+		s.lastID = max(s.lastID, e.LastID)
+		s.logID = e.LogID
+		s.version = version
+		s.events = 0
+	case eventCreated, eventUpdated, eventCompleted:
+		raw, err := migrateTask(e.Task, max(version, 1))
+		if err != nil {
+			return fmt.Errorf("failed to migrate task %d: %w", e.ID, err)
+		}
+		s.tasks[e.ID] = raw
+		s.lastID = max(s.lastID, e.ID)
+		s.events++
+	case eventDeleted:
+		delete(s.tasks, e.ID)
+		s.events++
+	default:
+		return fmt.Errorf("%s: unknown event type %q", s.filename, e.Type)
+	}
+
+	s.seq = e.Seq
+	return nil
+}
+
+// append writes events as one committed batch and syncs it to disk. A log that
+// does not exist yet is started with an empty snapshot.
+func (s *EventLogStorage) append(events []logEvent) error {
+	var buf bytes.Buffer
+	if s.logID == "" {
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			return fmt.Errorf("failed to create event log ID: %w", err)
+		}
+		snapshot := logEvent{Type: eventSnapshot, At: time.Now().UTC(), LogID: hex.EncodeToString(id), SchemaVersion: CurrentSchemaVersion, LastID: s.lastID, Commit: true}
+		if err := encodeEvent(&buf, snapshot); err != nil {
+			return err
+		}
+	}
+
+	for i := range events {
+		events[i].Seq = s.seq + int64(i) + 1
+		events[i].SchemaVersion = CurrentSchemaVersion
+		events[i].Commit = i == len(events)-1
+		if err := encodeEvent(&buf, events[i]); err != nil {
+			return err
+		}
+	}
+
+	if s.logID == "" {
+		// Write the first batch in one go so the log never exists without its snapshot
+		if err := writeFileAtomic(s.filename, buf.Bytes()); err != nil {
+			return err
+		}
+		s.reset()
+		return s.readFrom(0)
+	}
+
+	f, err := os.OpenFile(s.filename, os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open event log: %w", err)
+	}
+	defer f.Close()
+
+	// Drop a torn batch left behind by a crash before appending
+	if err := f.Truncate(s.size); err != nil {
+		return fmt.Errorf("failed to write event log: %w", err)
+	}
+	if _, err := f.WriteAt(buf.Bytes(), s.size); err != nil {
+		return fmt.Errorf("failed to write event log: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to write event log: %w", err)
+	}
+
+	for _, e := range events {
+		if err := s.apply(e); err != nil {
+			return err
+		}
+	}
+	s.size += int64(buf.Len())
+	if s.info, err = f.Stat(); err != nil {
+		return fmt.Errorf("failed to write event log: %w", err)
+	}
+	return nil
+}
+
+// compact replaces the log with a single snapshot of the current state. The
+// sequence number carries over, so the revision does not change.
+func (s *EventLogStorage) compact() error {
+	snapshot := logEvent{
+		Seq:           s.seq,
+		Type:          eventSnapshot,
+		At:            time.Now().UTC(),
+		LogID:         s.logID,
+		SchemaVersion: CurrentSchemaVersion,
+		LastID:        s.lastID,
+		Tasks:         []json.RawMessage{},
+		Commit:        true,
+	}
+	for _, id := range s.ids() {
+		snapshot.Tasks = append(snapshot.Tasks, s.tasks[id])
+	}
+
+	var buf bytes.Buffer
+	if err := encodeEvent(&buf, snapshot); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.filename, buf.Bytes()); err != nil {
+		return err
+	}
+
+	s.reset()
+	return s.readFrom(0)
+}
+
+// encodeEvent appends e to buf as a single line.
+func encodeEvent(buf *bytes.Buffer, e logEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	buf.Write(data)
+	buf.WriteByte('\n')
+	return nil
+}
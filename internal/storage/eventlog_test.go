@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amit9838/taskmanager/internal/task"
+)
+
+// readEventTypes returns the type of every line in the event log at path.
+func readEventTypes(t *testing.T, path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open event log: %v", err)
+	}
+	defer f.Close()
+
+	var types []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e logEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Invalid event line %q: %v", scanner.Text(), err)
+		}
+		types = append(types, string(e.Type))
+	}
+	return types
+}
+
+// TestEventLogSaveAndLoad tests that saves append events which replay to the same tasks
+func TestEventLogSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "tasks.jsonl")
+	s := NewEventLogStorage(path)
+
+	tasks, rev, err := s.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(tasks) != 0 || rev != "" {
+		t.Fatalf("Expected an empty log, got %d tasks at %q", len(tasks), rev)
+	}
+
+	rev, err = s.Save(newDBTasks(3), rev)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tasks, _, _ = s.Load()
+	tasks[0].Description = "Changed"
+	tasks[1].Status = task.StatusDone
+	if _, err := s.Save(tasks[:2], rev); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	want := "snapshot created created created updated completed deleted"
+	if got := strings.Join(readEventTypes(t, path), " "); got != want {
+		t.Errorf("Expected events %q, got %q", want, got)
+	}
+
+	loaded, _, err := NewEventLogStorage(path).Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(loaded) != 2 || loaded[0].Description != "Changed" || !loaded[1].IsDone() {
+		t.Errorf("Tasks not replayed correctly: %+v", loaded)
+	}
+}
+
+// TestEventLogRejectsStaleRevision tests that a second handle's write makes the first one's save conflict
+func TestEventLogRejectsStaleRevision(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.jsonl")
+	first := NewEventLogStorage(path)
+	second := NewEventLogStorage(path)
+
+	_, rev, _ := first.Load()
+	rev, _ = first.Save(newDBTasks(1), rev)
+
+	tasks, otherRev, _ := second.Load()
+	tasks[0].Description = "From second"
+	if _, err := second.Save(tasks, otherRev); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := first.Save(newDBTasks(2), rev); !errors.Is(err, task.ErrConflict) {
+		t.Fatalf("Expected conflict, got %v", err)
+	}
+
+	loaded, _, err := first.Load()
+	if err != nil || len(loaded) != 1 || loaded[0].Description != "From second" {
+		t.Errorf("Expected the other write to be visible, got %+v (%v)", loaded, err)
+	}
+}
+
+// TestEventLogIgnoresTornWrite tests that events without a commit after them are discarded
+func TestEventLogIgnoresTornWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.jsonl")
+	s := NewEventLogStorage(path)
+
+	_, rev, _ := s.Load()
+	rev, _ = s.Save(newDBTasks(2), rev)
+	intact, _ := os.Stat(path)
+
+	tasks, _, _ := s.Load()
+	tasks[0].Description = "Lost"
+	tasks[1].Description = "Lost too"
+	s.Save(tasks, rev)
+
+	// Keep the first event of the last batch and half of the second
+	data, _ := os.ReadFile(path)
+	batch := string(data[intact.Size():])
+	firstEnd := strings.Index(batch, "\n") + 1
+	torn := batch[:firstEnd+(len(batch)-firstEnd)/2]
+	if err := os.WriteFile(path, append(data[:intact.Size()], torn...), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	reopened := NewEventLogStorage(path)
+	loaded, rev, err := reopened.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(loaded) != 2 || loaded[0].Description != "Task 1" {
+		t.Fatalf("Expected the state before the torn batch, got %+v", loaded)
+	}
+
+	loaded[1].Description = "Written after recovery"
+	if _, err := reopened.Save(loaded, rev); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	again, _, err := NewEventLogStorage(path).Load()
+	if err != nil || len(again) != 2 || again[0].Description != "Task 1" || again[1].Description != "Written after recovery" {
+		t.Errorf("Expected the new write after the recovered state, got %+v (%v)", again, err)
+	}
+}
+
+// TestEventLogRejectsCorruption tests that a damaged line in the middle of the log is reported
+func TestEventLogRejectsCorruption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.jsonl")
+	s := NewEventLogStorage(path)
+
+	_, rev, _ := s.Load()
+	rev, _ = s.Save(newDBTasks(1), rev)
+	s.Save(newDBTasks(2), rev)
+
+	data, _ := os.ReadFile(path)
+	lines := strings.SplitAfter(string(data), "\n")
+	lines[1] = "{garbage\n"
+	os.WriteFile(path, []byte(strings.Join(lines, "")), 0644)
+
+	if _, _, err := NewEventLogStorage(path).Load(); err == nil || !strings.Contains(err.Error(), "corrupt") {
+		t.Errorf("Expected a corruption error, got %v", err)
+	}
+}
+
+// TestEventLogCompaction tests that the log is replaced by a snapshot once enough events pile up
+func TestEventLogCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.jsonl")
+	s := NewEventLogStorage(path)
+	s.compactAfter = 5
+
+	_, rev, _ := s.Load()
+	var err error
+	for i := 1; i <= 5; i++ {
+		if rev, err = s.Save(newDBTasks(i), rev); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	// The fifth event triggered a compaction
+	if got := strings.Join(readEventTypes(t, path), " "); got != "snapshot" {
+		t.Fatalf("Expected a single snapshot, got %q", got)
+	}
+
+	// A handle that read the log before the compaction sees the same state
+	other := NewEventLogStorage(path)
+	loaded, loadedRev, err := other.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(loaded) != 5 || loadedRev != rev {
+		t.Errorf("Expected 5 tasks at %s, got %d at %s", rev, len(loaded), loadedRev)
+	}
+
+	if _, err := other.Save(newDBTasks(6), loadedRev); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := strings.Join(readEventTypes(t, path), " "); got != "snapshot created" {
+		t.Errorf("Expected events to follow the snapshot, got %q", got)
+	}
+	if loaded, _, _ := s.Load(); len(loaded) != 6 {
+		t.Errorf("Expected 6 tasks, got %d", len(loaded))
+	}
+}
+
+// TestEventLogMigratesOlderSchema tests that tasks from an older snapshot and
+// from events without a schema version are migrated as they are replayed
+func TestEventLogMigratesOlderSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.jsonl")
+	old := `{"seq":0,"type":"snapshot","at":"2026-01-01T00:00:00Z","log_id":"0123456789abcdef","schema_version":1,"commit":true,` +
+		`"tasks":[{"id":1,"description":"Old","status":"done","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-02T00:00:00Z"}]}` + "\n" +
+		`{"seq":1,"type":"completed","at":"2026-01-03T00:00:00Z","id":2,"commit":true,` +
+		`"task":{"id":2,"description":"Later","status":"done","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-03T00:00:00Z"}}` + "\n"
+	if err := os.WriteFile(path, []byte(old), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	tasks, _, err := NewEventLogStorage(path).Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("Expected 2 tasks, got %d", len(tasks))
+	}
+	for _, got := range tasks {
+		if got.CompletedAt == nil || !got.CompletedAt.Equal(got.UpdatedAt) {
+			t.Errorf("Expected completed_at backfilled for task %d, got %v", got.ID, got.CompletedAt)
+		}
+	}
+}
+
+// TestEventLogRefusesNewerSchema tests that events appended by a newer version are not replayed
+func TestEventLogRefusesNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.jsonl")
+	s := NewEventLogStorage(path)
+	_, rev, _ := s.Load()
+	s.Save(newDBTasks(1), rev)
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"seq":99,"type":"updated","at":"2026-01-01T00:00:00Z","id":1,"schema_version":99,"commit":true,"task":{"id":1}}` + "\n")
+	f.Close()
+
+	if _, _, err := NewEventLogStorage(path).Load(); err == nil {
+		t.Fatal("Expected error replaying an event from a newer schema version")
+	}
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/amit9838/taskmanager/internal/task"
+)
+
+// JSONJournal stores the undo/redo journal in a JSON file next to the tasks file.
+type JSONJournal struct {
+	filename string
+}
+
+// NewJSONJournal creates a journal stored in the given file.
+// A missing or empty file is treated as an empty journal.
+func NewJSONJournal(filename string) *JSONJournal {
+	return &JSONJournal{filename: filename}
+}
+
+// SiblingPath derives the path of a companion file from the tasks file path,
+// e.g. "tasks.json" with suffix "journal" becomes "tasks.journal.json".
+func SiblingPath(tasksPath, suffix string) string {
+	ext := filepath.Ext(tasksPath)
+	return strings.TrimSuffix(tasksPath, ext) + "." + suffix + ".json"
+}
+
+// LoadJournal reads all journal entries, oldest first.
+func (j *JSONJournal) LoadJournal() ([]task.JournalEntry, error) {
+	data, err := os.ReadFile(j.filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, nil
+	}
+
+	var entries []task.JournalEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode journal: %w", err)
+	}
+
+	return entries, nil
+}
+
+// SaveJournal replaces the journal with the given entries.
+func (j *JSONJournal) SaveJournal(entries []task.JournalEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode journal: %w", err)
+	}
+
+	return writeFileAtomic(j.filename, data)
+}
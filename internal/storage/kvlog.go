@@ -0,0 +1,287 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// kvMagic starts every key-value log file and identifies its format version.
+const kvMagic = "TMKV0001"
+
+// kvHeaderSize is the size of a record header: CRC-32, op, key length, value length.
+const kvHeaderSize = 4 + 1 + 4 + 4
+
+// compactMinGarbage is how many bytes of superseded records a log may carry
+// before it is considered for compaction.
+const compactMinGarbage = 1 << 20
+
+// Record operations. Puts and deletes only take effect once a commit record
+// follows them, so a batch interrupted by a crash is ignored as a whole.
+const (
+	kvPut    byte = 1
+	kvDelete byte = 2
+	kvCommit byte = 3
+)
+
+// kvOp is a single change in a batch; a nil value deletes the key.
+type kvOp struct {
+	key   string
+	value []byte
+}
+
+// kvLog is a minimal append-only key-value store kept in a single file, in the
+// style of bitcask: every change is appended as a checksummed record and the
+// current value of every key is kept in memory. Superseded records are dropped
+// by rewriting the file once they outweigh the live data.
+//
+// kvLog does no locking of its own; writers must hold the file lock.
+type kvLog struct {
+	path string
+	data map[string][]byte
+	// size is the offset just past the last commit that has been read or written.
+	size int64
+	// info identifies the file that was read, to notice when it is replaced.
+	info os.FileInfo
+	// live and garbage are the bytes taken by current and superseded records.
+	live    int64
+	garbage int64
+}
+
+// openKVLog opens the log at path, creating an empty one if it does not exist.
+func openKVLog(path string) (*kvLog, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("could not create data directory: %w", err)
+		}
+		if err := writeFileAtomic(path, []byte(kvMagic)); err != nil {
+			return nil, err
+		}
+	}
+
+	l := &kvLog{path: path}
+	if err := l.reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// get returns the value stored under key.
+func (l *kvLog) get(key string) ([]byte, bool) {
+	v, ok := l.data[key]
+	return v, ok
+}
+
+// keys returns every key with the given prefix in sorted order.
+func (l *kvLog) keys(prefix string) []string {
+	var keys []string
+	for k := range l.data {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// refresh picks up commits written by other processes since the log was last read.
+func (l *kvLog) refresh() error {
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return fmt.Errorf("failed to read database: %w", err)
+	}
+
+	switch {
+	case !os.SameFile(info, l.info) || info.Size() < l.size:
+		// Replaced by a compaction in another process
+		return l.reload()
+	case info.Size() > l.size:
+		return l.readFrom(l.size)
+	}
+	return nil
+}
+
+// reload discards the in-memory state and reads the whole file.
+func (l *kvLog) reload() error {
+	l.data = map[string][]byte{}
+	l.live, l.garbage = 0, 0
+	return l.readFrom(0)
+}
+
+// readFrom reads and applies the committed records after offset. Anything after
+// the last valid commit, such as a batch torn by a crash, is ignored.
+func (l *kvLog) readFrom(offset int64) error {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return fmt.Errorf("failed to read database: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read database: %w", err)
+	}
+
+	if offset == 0 {
+		magic := make([]byte, len(kvMagic))
+		if _, err := io.ReadFull(f, magic); err != nil || string(magic) != kvMagic {
+			return fmt.Errorf("%s is not a tm database", l.path)
+		}
+		offset = int64(len(kvMagic))
+		l.size = offset
+	}
+
+	rest := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(rest, offset); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read database: %w", err)
+	}
+
+	var pending []kvOp
+	pos := 0
+	for {
+		op, key, value, n, ok := decodeRecord(rest[pos:])
+		if !ok {
+			break
+		}
+		pos += n
+
+		switch op {
+		case kvPut:
+			pending = append(pending, kvOp{key: key, value: value})
+		case kvDelete:
+			pending = append(pending, kvOp{key: key})
+		case kvCommit:
+			l.apply(pending)
+			pending = nil
+			l.size = offset + int64(pos)
+		}
+	}
+
+	l.info = info
+	return nil
+}
+
+// write appends ops as one committed batch and syncs it to disk, compacting the
+// file afterwards if it has accumulated too much garbage.
+func (l *kvLog) write(ops []kvOp) error {
+	var buf bytes.Buffer
+	for _, op := range ops {
+		if op.value == nil {
+			encodeRecord(&buf, kvDelete, op.key, nil)
+		} else {
+			encodeRecord(&buf, kvPut, op.key, op.value)
+		}
+	}
+	encodeRecord(&buf, kvCommit, "", nil)
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer f.Close()
+
+	// Drop a torn batch left behind by a crash before appending
+	if err := f.Truncate(l.size); err != nil {
+		return fmt.Errorf("failed to write database: %w", err)
+	}
+	if _, err := f.WriteAt(buf.Bytes(), l.size); err != nil {
+		return fmt.Errorf("failed to write database: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to write database: %w", err)
+	}
+
+	l.apply(ops)
+	l.size += int64(buf.Len())
+	if l.info, err = f.Stat(); err != nil {
+		return fmt.Errorf("failed to write database: %w", err)
+	}
+
+	if l.garbage > compactMinGarbage && l.garbage > l.live {
+		return l.compact()
+	}
+	return nil
+}
+
+// compact rewrites the file with only the current value of every key.
+func (l *kvLog) compact() error {
+	var buf bytes.Buffer
+	buf.WriteString(kvMagic)
+	for _, key := range l.keys("") {
+		encodeRecord(&buf, kvPut, key, l.data[key])
+	}
+	encodeRecord(&buf, kvCommit, "", nil)
+
+	if err := writeFileAtomic(l.path, buf.Bytes()); err != nil {
+		return err
+	}
+	return l.reload()
+}
+
+// apply updates the in-memory state with a committed batch.
+func (l *kvLog) apply(ops []kvOp) {
+	for _, op := range ops {
+		if old, ok := l.data[op.key]; ok {
+			size := recordSize(op.key, old)
+			l.live -= size
+			l.garbage += size
+		}
+		if op.value == nil {
+			delete(l.data, op.key)
+			l.garbage += recordSize(op.key, nil)
+			continue
+		}
+		l.data[op.key] = op.value
+		l.live += recordSize(op.key, op.value)
+	}
+}
+
+func recordSize(key string, value []byte) int64 {
+	return int64(kvHeaderSize + len(key) + len(value))
+}
+
+// encodeRecord appends a record: a CRC-32 of everything after it, the op,
+// the key and value lengths, then the key and value themselves.
+func encodeRecord(buf *bytes.Buffer, op byte, key string, value []byte) {
+	body := make([]byte, kvHeaderSize-4, kvHeaderSize-4+len(key)+len(value))
+	body[0] = op
+	binary.LittleEndian.PutUint32(body[1:], uint32(len(key)))
+	binary.LittleEndian.PutUint32(body[5:], uint32(len(value)))
+	body = append(body, key...)
+	body = append(body, value...)
+
+	var crc [4]byte
+	binary.LittleEndian.PutUint32(crc[:], crc32.ChecksumIEEE(body))
+	buf.Write(crc[:])
+	buf.Write(body)
+}
+
+// decodeRecord reads the record at the start of data. It reports false if data
+// holds no complete, intact record.
+func decodeRecord(data []byte) (op byte, key string, value []byte, n int, ok bool) {
+	if len(data) < kvHeaderSize {
+		return 0, "", nil, 0, false
+	}
+
+	keyLen := int(binary.LittleEndian.Uint32(data[5:]))
+	valueLen := int(binary.LittleEndian.Uint32(data[9:]))
+	n = kvHeaderSize + keyLen + valueLen
+	if keyLen < 0 || valueLen < 0 || n > len(data) {
+		return 0, "", nil, 0, false
+	}
+	if crc32.ChecksumIEEE(data[4:n]) != binary.LittleEndian.Uint32(data) {
+		return 0, "", nil, 0, false
+	}
+
+	op = data[4]
+	key = string(data[kvHeaderSize : kvHeaderSize+keyLen])
+	value = bytes.Clone(data[kvHeaderSize+keyLen : n])
+	return op, key, value, n, true
+}
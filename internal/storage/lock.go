@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultLockTimeout is how long Lock waits for another process to release the tasks file.
+const DefaultLockTimeout = 5 * time.Second
+
+// lockRetryInterval is how often Lock retries while the file is held elsewhere.
+const lockRetryInterval = 50 * time.Millisecond
+
+// ErrLockTimeout is returned when the tasks file stays locked by another process
+// for longer than the lock timeout.
+var ErrLockTimeout = errors.New("timed out waiting for lock")
+
+// SetLockTimeout sets how long Lock waits before giving up.
+// A zero timeout tries once and fails immediately if the file is locked.
+func (s *JSONStorage) SetLockTimeout(timeout time.Duration) {
+	s.lockTimeout = timeout
+}
+
+// Lock takes an exclusive advisory lock that guards a whole load-modify-save cycle
+// against other processes using the same tasks file. The lock is held on a separate
+// "<file>.lock" file, because saving replaces the tasks file itself.
+// The returned function releases the lock.
+func (s *JSONStorage) Lock() (func(), error) {
+	return lockFile(s.filename, s.lockTimeout)
+}
+
+// lockFile takes an exclusive advisory lock on "<filename>.lock", waiting up to
+// timeout for other processes to release it.
+func lockFile(filename string, timeout time.Duration) (func(), error) {
+	path := filename + ".lock"
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("could not create data directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("could not lock %s: %w", filename, err)
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%w on %s after %s; another tm command may still be running", ErrLockTimeout, filename, timeout)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+
+	return func() {
+		unlock(f)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,34 @@
+//go:build unix
+
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// TestLockTimeout tests that a second storage cannot lock the file while the first holds it
+func TestLockTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	first := NewJSONStorage(path)
+	second := NewJSONStorage(path)
+	second.SetLockTimeout(0)
+
+	unlock, err := first.Lock()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := second.Lock(); !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("Expected lock timeout, got %v", err)
+	}
+
+	unlock()
+
+	unlockSecond, err := second.Lock()
+	if err != nil {
+		t.Fatalf("Expected lock after release, got %v", err)
+	}
+	unlockSecond()
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// EnvFile is the environment variable that overrides the default tasks file location.
+const EnvFile = "TM_FILE"
+
+// EnvLockTimeout is the environment variable that overrides DefaultLockTimeout.
+const EnvLockTimeout = "TM_LOCK_TIMEOUT"
+
+// ResolvePath determines which tasks file to use.
+// An explicit path (usually from the --file flag) wins, then the TM_FILE
+// environment variable, and finally the XDG data directory default.
+func ResolvePath(explicit string) (string, error) {
+	if explicit != "" {
+		return explicit, nil
+	}
+
+	if env := os.Getenv(EnvFile); env != "" {
+		return env, nil
+	}
+
+	return DefaultPath()
+}
+
+// ResolveLockTimeout determines how long to wait for the tasks file lock.
+// An explicit duration such as "10s" (usually from the --lock-timeout flag) wins,
+// then the TM_LOCK_TIMEOUT environment variable, and finally DefaultLockTimeout.
+func ResolveLockTimeout(explicit string) (time.Duration, error) {
+	value, source := explicit, "--lock-timeout"
+	if value == "" {
+		value, source = os.Getenv(EnvLockTimeout), EnvLockTimeout
+	}
+	if value == "" {
+		return DefaultLockTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid %s %q (use a duration such as 10s)", source, value)
+	}
+	return timeout, nil
+}
+
+// DefaultPath returns the default location of the tasks file,
+// $XDG_DATA_HOME/tm/tasks.json or ~/.local/share/tm/tasks.json when XDG_DATA_HOME is unset.
+func DefaultPath() (string, error) {
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not determine home directory: %w", err)
+		}
+		dataDir = filepath.Join(home, ".local", "share")
+	}
+
+	return filepath.Join(dataDir, "tm", "tasks.json"), nil
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amit9838/taskmanager/internal/task"
+)
+
+// TestResolvePath tests that --file wins over TM_FILE, which wins over the XDG default
+func TestResolvePath(t *testing.T) {
+	home := t.TempDir()
+	xdg := t.TempDir()
+
+	cases := []struct {
+		name     string
+		explicit string
+		env      string
+		xdgData  string
+		want     string
+	}{
+		{"Flag wins over everything", "flag.json", "env.json", xdg, "flag.json"},
+		{"Environment wins over the default", "", "env.json", xdg, "env.json"},
+		{"XDG data directory", "", "", xdg, filepath.Join(xdg, "tm", "tasks.json")},
+		{"Home directory without XDG", "", "", "", filepath.Join(home, ".local", "share", "tm", "tasks.json")},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv("HOME", home)
+			t.Setenv(EnvFile, c.env)
+			t.Setenv("XDG_DATA_HOME", c.xdgData)
+
+			got, err := ResolvePath(c.explicit)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got != c.want {
+				t.Errorf("Expected %s, got %s", c.want, got)
+			}
+		})
+	}
+}
+
+// TestCustomFilename tests that tasks are stored in the file the storage was given
+func TestCustomFilename(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	path := filepath.Join(dir, "nested", "work-tasks.json")
+
+	s := NewJSONStorage(path)
+	if s.Path() != path {
+		t.Errorf("Expected path %s, got %s", path, s.Path())
+	}
+
+	_, rev, err := s.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := s.Save([]task.Task{{ID: 1, Description: "Custom", Status: task.StatusPending}}, rev); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected tasks in %s: %v", path, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tasks.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected no tasks.json in the working directory, got %v", err)
+	}
+}
+
+// TestResolveLockTimeout tests that --lock-timeout wins over TM_LOCK_TIMEOUT and invalid values are rejected
+func TestResolveLockTimeout(t *testing.T) {
+	cases := []struct {
+		name     string
+		explicit string
+		env      string
+		want     time.Duration
+		wantErr  string
+	}{
+		{"Flag wins over the environment", "10s", "1m", 10 * time.Second, ""},
+		{"Environment wins over the default", "", "1m", time.Minute, ""},
+		{"Default", "", "", DefaultLockTimeout, ""},
+		{"Zero fails immediately", "0s", "", 0, ""},
+		{"Invalid flag", "soon", "1m", 0, "--lock-timeout"},
+		{"Invalid environment", "", "soon", 0, EnvLockTimeout},
+		{"Negative duration", "-1s", "", 0, "--lock-timeout"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv(EnvLockTimeout, c.env)
+
+			got, err := ResolveLockTimeout(c.explicit)
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("Expected an error naming %s, got %v", c.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got != c.want {
+				t.Errorf("Expected %s, got %s", c.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// CurrentSchemaVersion is the version of the file format written by Save.
+// Bump it together with a new entry in migrations whenever the stored form of a
+// task changes in a way older code would misread.
+const CurrentSchemaVersion = 6
+
+// envelope is the on-disk form of a tasks file from schema version 1 on.
+type envelope struct {
+	SchemaVersion int `json:"schema_version"`
+	// LastID is the highest task ID ever saved, from schema version 6 on.
+	LastID int               `json:"last_id,omitempty"`
+	Tasks  []json.RawMessage `json:"tasks"`
+}
+
+// migration upgrades the raw contents of a file from one schema version to the next.
+// Migrations work on raw JSON rather than task.Task so they keep describing the
+// format of their own version when Task changes later.
+type migration struct {
+	from    int
+	migrate func(data []byte) ([]byte, error)
+}
+
+// migrations holds one step per schema version, in order; migrations[i] upgrades
+// version i to version i+1.
+// Versions that only add optional fields still get a step of their own: older
+// releases would drop the field when saving, so they must refuse the file.
+var migrations = []migration{
+	{from: 0, migrate: migrateBareArray},
+	{from: 1, migrate: migrateCompletedAt},
+	{from: 2, migrate: setVersion(3)}, // adds tracked time
+	{from: 3, migrate: setVersion(4)}, // adds pomodoro sessions to tracked time
+	{from: 4, migrate: setVersion(5)}, // adds estimates
+	{from: 5, migrate: setVersion(6)}, // adds the highest task ID ever saved
+}
+
+// schemaVersion reports which schema version data was written with.
+// Files that are a bare JSON array predate versioning and are version 0.
+func schemaVersion(data []byte) (int, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] == '[' {
+		return 0, nil
+	}
+
+	var header struct {
+		SchemaVersion *int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(trimmed, &header); err != nil {
+		return 0, fmt.Errorf("failed to decode tasks: %w", err)
+	}
+	if header.SchemaVersion == nil {
+		return 0, fmt.Errorf("failed to decode tasks: missing schema_version")
+	}
+	return *header.SchemaVersion, nil
+}
+
+// migrate upgrades data to CurrentSchemaVersion. It refuses files written by a
+// newer version, which may contain data this version would silently drop.
+func migrate(data []byte, version int) ([]byte, error) {
+	if version > CurrentSchemaVersion {
+		return nil, fmt.Errorf("tasks file uses schema version %d, but this version of tm only supports up to %d; please upgrade tm", version, CurrentSchemaVersion)
+	}
+
+	for _, m := range migrations[version:] {
+		var err error
+		if data, err = m.migrate(data); err != nil {
+			return nil, fmt.Errorf("failed to migrate tasks from schema version %d: %w", m.from, err)
+		}
+	}
+	return data, nil
+}
+
+// migrateTask upgrades a single stored task from the given schema version, which
+// must be 1 or later, for backends that keep each task as a record of its own.
+func migrateTask(raw json.RawMessage, version int) (json.RawMessage, error) {
+	if version == CurrentSchemaVersion {
+		return raw, nil
+	}
+
+	data, err := json.Marshal(envelope{SchemaVersion: version, Tasks: []json.RawMessage{raw}})
+	if err != nil {
+		return nil, err
+	}
+	if data, err = migrate(data, version); err != nil {
+		return nil, err
+	}
+
+	var file envelope
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	return file.Tasks[0], nil
+}
+
+// backupPath returns where the contents of filename are kept before migrating
+// from the given schema version, e.g. "tasks.json.v0.bak".
+func backupPath(filename string, version int) string {
+	return fmt.Sprintf("%s.v%d.bak", filename, version)
+}
+
+// writeBackup saves the pre-migration contents of filename. An existing backup
+// is left alone so the oldest copy of that version survives.
+func writeBackup(filename string, version int, data []byte) error {
+	f, err := os.OpenFile(backupPath(filename, version), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not create backup: %w", err)
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("could not write backup: %w", err)
+	}
+	return f.Close()
+}
+
+// migrateBareArray wraps a version 0 bare array in an envelope. Tasks written
+// before the status lifecycle only carry a done flag, which becomes a status.
+func migrateBareArray(data []byte) ([]byte, error) {
+	var tasks []map[string]json.RawMessage
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &tasks); err != nil {
+			return nil, err
+		}
+	}
+
+	raw := make([]json.RawMessage, len(tasks))
+	for i, t := range tasks {
+		if _, ok := t["status"]; !ok {
+			status := `"pending"`
+			if string(t["done"]) == "true" {
+				status = `"done"`
+			}
+			t["status"] = json.RawMessage(status)
+		}
+		delete(t, "done")
+
+		encoded, err := json.Marshal(t)
+		if err != nil {
+			return nil, err
+		}
+		raw[i] = encoded
+	}
+
+	return json.Marshal(envelope{SchemaVersion: 1, Tasks: raw})
+}
+
+// setVersion returns a migration for a version that only added optional fields.
+// Older files are valid as they are and just get the new version number, which
+// makes older releases of tm refuse the file instead of dropping the new fields.
+func setVersion(version int) func(data []byte) ([]byte, error) {
+	return func(data []byte) ([]byte, error) {
+		var file envelope
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, err
+		}
+		file.SchemaVersion = version
+		return json.Marshal(file)
+	}
+}
+
+// migrateCompletedAt moves a version 1 file to version 2, which records when
+// tasks were completed. Done tasks get their last update time, the best
+// estimate available, so they are not missing from completion reports.
+// The database and event log run it on each of their tasks through migrateTask.
+func migrateCompletedAt(data []byte) ([]byte, error) {
+	var file envelope
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	for i, raw := range file.Tasks {
+		var t map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return nil, err
+		}
+		if _, ok := t["completed_at"]; ok || string(t["status"]) != `"done"` {
+			continue
+		}
+
+		completed, ok := t["updated_at"]
+		if !ok || string(completed) == `"0001-01-01T00:00:00Z"` {
+			completed = t["created_at"]
+		}
+		t["completed_at"] = completed
+
+		encoded, err := json.Marshal(t)
+		if err != nil {
+			return nil, err
+		}
+		file.Tasks[i] = encoded
+	}
+
+	file.SchemaVersion = 2
+	return json.Marshal(file)
+}
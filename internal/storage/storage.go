@@ -1,85 +1,269 @@
 package storage
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/amit9838/taskmanager/internal/task"
 )
 
 type JSONStorage struct {
-	filename string
+	filename    string
+	lockTimeout time.Duration
 }
 
 // NewJSONStorage creates a new JSONStorage instance with the given filename.
 // The filename is used to store and load tasks from a local file.
-// If the file does not exist, it will be created.
+// If the file does not exist, it will be created along with any missing parent directories.
 // If the file is empty, an empty task list will be returned.
-// The filename is relative to the current working directory when running locally,
-// and is relative to the home directory of the current user when building a binary.
 func NewJSONStorage(filename string) *JSONStorage {
+	return &JSONStorage{filename: filename, lockTimeout: DefaultLockTimeout}
+}
 
-	// home directory of the current user
-	// Use this when building binary
-	// home, _ := os.UserHomeDir()
-	// path := filepath.Join(home, filename)
-
-	// relative path when running locally
-	path := "tasks.json"
-	return &JSONStorage{filename: path}
+// Path returns the location of the underlying JSON file.
+func (s *JSONStorage) Path() string {
+	return s.filename
 }
 
 // Load reads tasks from the JSON file specified during initialization of the storage.
 // If the file does not exist, it will be created with an empty task list.
 // If the file is empty, an empty task list will be returned.
+// Files written with an older schema version are migrated in memory; the file itself
+// is only upgraded by the next Save, which holds the Lock. Files from a newer version
+// are refused.
+// The returned revision identifies the file contents that were read.
 // The function returns an error if there was an issue reading or decoding the file.
 // The error will contain more information about the issue.
-func (s *JSONStorage) Load() ([]task.Task, error) {
+func (s *JSONStorage) Load() ([]task.Task, task.Revision, error) {
 	if _, err := os.Stat(s.filename); os.IsNotExist(err) {
-		initialData := []byte("[]")
-		err := os.WriteFile(s.filename, initialData, 0644)
+		if err := os.MkdirAll(filepath.Dir(s.filename), 0755); err != nil {
+			return nil, "", fmt.Errorf("could not create data directory: %w", err)
+		}
+		initialData, err := encodeTasks([]task.Task{}, 0)
 		if err != nil {
-			return nil, fmt.Errorf("could not create file: %w", err)
+			return nil, "", err
 		}
-		return []task.Task{}, nil
+		if err := os.WriteFile(s.filename, initialData, 0644); err != nil {
+			return nil, "", fmt.Errorf("could not create file: %w", err)
+		}
+		return []task.Task{}, revisionOf(initialData), nil
 	}
 
 	data, err := os.ReadFile(s.filename)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		return nil, "", fmt.Errorf("failed to read file: %w", err)
 	}
 
-	if len(data) == 0 {
-		return []task.Task{}, nil
+	if len(bytes.TrimSpace(data)) == 0 {
+		return []task.Task{}, revisionOf(data), nil
 	}
 
-	var tasks []task.Task
-	if err := json.Unmarshal(data, &tasks); err != nil {
-		return nil, fmt.Errorf("failed to decode tasks: %w", err)
+	version, err := schemaVersion(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	migrated, err := migrate(data, version)
+	if err != nil {
+		return nil, "", err
+	}
+	tasks, err := decodeTasks(migrated)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := lastIDOf(migrated); err != nil {
+		return nil, "", err
 	}
 
-	return tasks, nil
+	// The revision is of the file as read, so a Save based on it notices any
+	// write that landed since, including another process's upgrade
+	return tasks, revisionOf(data), nil
 }
 
-// Save writes the provided tasks to the JSON file specified during initialization of the storage.
+// Save writes the provided tasks to the JSON file specified during initialization of the storage
+// and returns the new revision. The write is rejected with task.ErrConflict if the file no longer
+// matches base, the revision returned by the Load the tasks came from.
+// The file also keeps the highest task ID ever saved; see LastID. A file from an older
+// schema version is backed up before it is replaced.
+// Hold the Lock while loading and saving so that nothing can change the file in between.
 // If an error occurs while encoding or writing the tasks, an error will be returned.
 // The error will contain more information about the issue.
-func (s *JSONStorage) Save(tasks []task.Task) error {
-	data, err := json.MarshalIndent(tasks, "", "  ")
+func (s *JSONStorage) Save(tasks []task.Task, base task.Revision) (task.Revision, error) {
+	current, rev, err := s.current()
+	if err != nil {
+		return "", err
+	}
+	if rev != base {
+		return "", fmt.Errorf("%w: %s was modified since it was loaded", task.ErrConflict, s.filename)
+	}
+
+	if err := s.backupOlder(current); err != nil {
+		return "", err
+	}
+	lastID, err := lastIDOf(current)
+	if err != nil {
+		return "", err
+	}
+	data, err := encodeTasks(tasks, lastID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := writeFileAtomic(s.filename, data); err != nil {
+		return "", err
+	}
+	return revisionOf(data), nil
+}
+
+// LastID returns the highest task ID ever saved to the file, including the IDs
+// of tasks that have since been purged.
+func (s *JSONStorage) LastID() (int, error) {
+	data, _, err := s.current()
+	if err != nil {
+		return 0, err
+	}
+	return lastIDOf(data)
+}
+
+// ReserveID raises the highest task ID kept in the file to id.
+// Hold the Lock while calling it.
+func (s *JSONStorage) ReserveID(id int) error {
+	tasks, _, err := s.Load()
+	if err != nil {
+		return err
+	}
+	current, _, err := s.current()
+	if err != nil {
+		return err
+	}
+	last, err := lastIDOf(current)
+	if err != nil || id <= last {
+		return err
+	}
+
+	if err := s.backupOlder(current); err != nil {
+		return err
+	}
+	data, err := encodeTasks(tasks, id)
 	if err != nil {
-		return fmt.Errorf("failed to encode tasks: %w", err)
+		return err
+	}
+	return writeFileAtomic(s.filename, data)
+}
+
+// encodeTasks renders tasks as a file in the current schema version. The
+// highest ID ever saved is the larger of lastID and the IDs in tasks.
+func encodeTasks(tasks []task.Task, lastID int) ([]byte, error) {
+	if tasks == nil {
+		tasks = []task.Task{}
+	}
+	file := struct {
+		SchemaVersion int         `json:"schema_version"`
+		LastID        int         `json:"last_id"`
+		Tasks         []task.Task `json:"tasks"`
+	}{CurrentSchemaVersion, max(lastID, highestID(tasks)), tasks}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode tasks: %w", err)
+	}
+	return data, nil
+}
+
+// decodeTasks reads the tasks from a file in the current schema version.
+func decodeTasks(data []byte) ([]task.Task, error) {
+	var file struct {
+		Tasks []task.Task `json:"tasks"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode tasks: %w", err)
+	}
+	if file.Tasks == nil {
+		file.Tasks = []task.Task{}
+	}
+	return file.Tasks, nil
+}
+
+// backupOlder keeps a copy of data, the file about to be replaced, if it was
+// written with an older schema version.
+func (s *JSONStorage) backupOlder(data []byte) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	version, err := schemaVersion(data)
+	if err != nil || version >= CurrentSchemaVersion {
+		return err
+	}
+	return writeBackup(s.filename, version, data)
+}
+
+// current returns the file as it is on disk together with its revision, or
+// no data and an empty revision if it does not exist yet.
+func (s *JSONStorage) current() ([]byte, task.Revision, error) {
+	data, err := os.ReadFile(s.filename)
+	if os.IsNotExist(err) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return data, revisionOf(data), nil
+}
+
+// lastIDOf returns the highest task ID recorded in a tasks file, or 0 if the
+// file does not record one.
+func lastIDOf(data []byte) (int, error) {
+	var header struct {
+		LastID int `json:"last_id"`
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] == '[' {
+		return 0, nil
+	}
+	if err := json.Unmarshal(trimmed, &header); err != nil {
+		return 0, fmt.Errorf("failed to decode last_id: %w", err)
+	}
+	return header.LastID, nil
+}
+
+// highestID returns the highest ID in tasks, or 0 if there are none.
+func highestID(tasks []task.Task) int {
+	id := 0
+	for _, t := range tasks {
+		id = max(id, t.ID)
+	}
+	return id
+}
+
+// revisionOf derives a revision from file contents, so any change to the file,
+// whoever made it, produces a different revision.
+func revisionOf(data []byte) task.Revision {
+	sum := sha256.Sum256(data)
+	return task.Revision(hex.EncodeToString(sum[:8]))
+}
+
+// writeFileAtomic writes data to a temporary file next to filename and renames it
+// into place, creating missing parent directories first.
+func writeFileAtomic(filename string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("could not create data directory: %w", err)
 	}
 
 	// Write to temporary file first
-	tempFile := s.filename + ".tmp"
+	tempFile := filename + ".tmp"
 	if err := os.WriteFile(tempFile, data, 0644); err != nil {
 		return fmt.Errorf("failed to write temporary file: %w", err)
 	}
 
 	// Atomic rename
-	if err := os.Rename(tempFile, s.filename); err != nil {
+	if err := os.Rename(tempFile, filename); err != nil {
 		return fmt.Errorf("failed to rename temporary file: %w", err)
 	}
 
@@ -0,0 +1,281 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amit9838/taskmanager/internal/task"
+)
+
+// TestLoadMigratesDoneFlag tests that files written before the status lifecycle still load
+// and are upgraded by the next save
+func TestLoadMigratesDoneFlag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	legacy := `[
+  {"id": 1, "description": "Old open task", "done": false, "created_at": "2024-01-15T10:00:00Z"},
+  {"id": 2, "description": "Old done task", "done": true, "created_at": "2024-01-15T10:00:00Z"}
+]`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	s := NewJSONStorage(path)
+	tasks, rev, err := s.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(tasks) != 2 {
+		t.Fatalf("Expected 2 tasks, got %d", len(tasks))
+	}
+	if tasks[0].Status != task.StatusPending {
+		t.Errorf("Expected task 1 to be pending, got %s", tasks[0].Status)
+	}
+	if tasks[1].Status != task.StatusDone {
+		t.Errorf("Expected task 2 to be done, got %s", tasks[1].Status)
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != legacy {
+		t.Errorf("Load should not rewrite the file, got %q", data)
+	}
+	if _, err := s.Save(tasks, rev); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	backup, err := os.ReadFile(path + ".v0.bak")
+	if err != nil || string(backup) != legacy {
+		t.Errorf("Expected the original file as backup, got %q (%v)", backup, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read migrated file: %v", err)
+	}
+	if version, err := schemaVersion(data); err != nil || version != CurrentSchemaVersion {
+		t.Errorf("Expected file saved with schema version %d, got %d (%v)", CurrentSchemaVersion, version, err)
+	}
+}
+
+// TestLoadBackfillsCompletedAt tests that done tasks from schema version 1 get a completion time
+func TestLoadBackfillsCompletedAt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	v1 := `{"schema_version": 1, "tasks": [
+  {"id": 1, "description": "Open", "status": "pending", "created_at": "2026-01-10T10:00:00Z", "updated_at": "2026-01-11T10:00:00Z"},
+  {"id": 2, "description": "Done", "status": "done", "created_at": "2026-01-10T10:00:00Z", "updated_at": "2026-01-12T10:00:00Z"},
+  {"id": 3, "description": "Done long ago", "status": "done", "created_at": "2026-01-10T10:00:00Z", "updated_at": "0001-01-01T00:00:00Z"}
+]}`
+	if err := os.WriteFile(path, []byte(v1), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	s := NewJSONStorage(path)
+	tasks, rev, err := s.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if tasks[0].CompletedAt != nil {
+		t.Errorf("Expected open task without completion time, got %v", tasks[0].CompletedAt)
+	}
+	want := map[int]string{1: "2026-01-12T10:00:00Z", 2: "2026-01-10T10:00:00Z"}
+	for i, completed := range want {
+		if tasks[i].CompletedAt == nil || tasks[i].CompletedAt.Format(time.RFC3339) != completed {
+			t.Errorf("Expected task %d completed at %s, got %v", tasks[i].ID, completed, tasks[i].CompletedAt)
+		}
+	}
+
+	if _, err := s.Save(tasks, rev); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := os.Stat(path + ".v1.bak"); err != nil {
+		t.Errorf("Expected a backup of the version 1 file: %v", err)
+	}
+}
+
+// TestLoadDoesNotOverwriteConcurrentSave tests that reading an older file does not
+// replace a save another process made after the read
+func TestLoadDoesNotOverwriteConcurrentSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	v1 := `{"schema_version": 1, "tasks": [{"id": 1, "description": "Old", "status": "pending"}]}`
+	if err := os.WriteFile(path, []byte(v1), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	reader, writer := NewJSONStorage(path), NewJSONStorage(path)
+	_, stale, err := reader.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Another process saves under the lock after the read
+	unlock, err := writer.Lock()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	tasks, rev, err := writer.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	tasks = append(tasks, task.Task{ID: 2, Description: "New", Status: task.StatusPending})
+	if _, err := writer.Save(tasks, rev); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	unlock()
+
+	// Whatever the reader does next must keep the other save
+	got, _, err := reader.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("Expected the concurrent save to survive, got %d tasks", len(got))
+	}
+	if _, err := reader.Save(got[:1], stale); !errors.Is(err, task.ErrConflict) {
+		t.Errorf("Expected a conflict saving from the stale read, got %v", err)
+	}
+}
+
+// TestLoadRefusesNewerSchema tests that files from a newer version are left untouched
+func TestLoadRefusesNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	future := `{"schema_version": 99, "tasks": []}`
+	if err := os.WriteFile(path, []byte(future), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	if _, _, err := NewJSONStorage(path).Load(); err == nil {
+		t.Fatal("Expected error loading a newer schema version")
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != future {
+		t.Errorf("File should not be modified, got %q", data)
+	}
+}
+
+// TestLoadRejectsMalformedLastID tests that an unreadable last_id is reported instead of reset
+func TestLoadRejectsMalformedLastID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	broken := `{"schema_version": 6, "last_id": "twelve", "tasks": []}`
+	if err := os.WriteFile(path, []byte(broken), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	s := NewJSONStorage(path)
+	if _, _, err := s.Load(); err == nil {
+		t.Error("Expected error loading a malformed last_id")
+	}
+	if _, err := s.LastID(); err == nil {
+		t.Error("Expected error reading a malformed last_id")
+	}
+}
+
+// TestSaveAndLoad tests a round trip through a file in a missing directory
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "dir", "tasks.json")
+	s := NewJSONStorage(path)
+
+	tasks, rev, err := s.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(tasks) != 0 {
+		t.Fatalf("Expected empty task list, got %d tasks", len(tasks))
+	}
+
+	tasks = append(tasks, task.Task{ID: 1, Description: "Write tests", Status: task.StatusInProgress, Priority: task.PriorityHigh})
+	saved, err := s.Save(tasks, rev)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	loaded, loadedRev, err := s.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if loadedRev != saved {
+		t.Errorf("Expected revision %s after save, got %s", saved, loadedRev)
+	}
+	if len(loaded) != 1 || loaded[0].Status != task.StatusInProgress || loaded[0].Priority != task.PriorityHigh {
+		t.Errorf("Task not round-tripped correctly: %+v", loaded)
+	}
+}
+
+// TestSaveRejectsStaleRevision tests that a save based on an outdated load fails with a conflict
+func TestSaveRejectsStaleRevision(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	s := NewJSONStorage(path)
+
+	tasks, rev, err := s.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Another process changes the file in the meantime
+	other := append(tasks, task.Task{ID: 1, Description: "Written elsewhere", Status: task.StatusPending})
+	if _, err := NewJSONStorage(path).Save(other, rev); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := s.Save(tasks, rev); !errors.Is(err, task.ErrConflict) {
+		t.Fatalf("Expected conflict, got %v", err)
+	}
+
+	loaded, _, err := s.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(loaded) != 1 {
+		t.Errorf("Expected the other write to survive, got %d tasks", len(loaded))
+	}
+}
+
+// TestKeepsLastID tests that every backend remembers the IDs of removed tasks
+func TestKeepsLastID(t *testing.T) {
+	type store interface {
+		task.Repository
+		task.IDKeeper
+	}
+	backends := map[string]func(dir string) store{
+		"json":     func(dir string) store { return NewJSONStorage(filepath.Join(dir, "tasks.json")) },
+		"db":       func(dir string) store { return NewDBStorage(filepath.Join(dir, "tasks.db")) },
+		"eventlog": func(dir string) store { return NewEventLogStorage(filepath.Join(dir, "tasks.jsonl")) },
+	}
+
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			s := open(dir)
+
+			_, rev, _ := s.Load()
+			rev, err := s.Save(newDBTasks(3), rev)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if _, err := s.Save(newDBTasks(1), rev); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			reopened := open(dir)
+			if last, err := reopened.LastID(); err != nil || last != 3 {
+				t.Fatalf("Expected last ID 3 after removing tasks, got %d (%v)", last, err)
+			}
+
+			if err := reopened.ReserveID(10); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if err := reopened.ReserveID(5); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if last, _ := open(dir).LastID(); last != 10 {
+				t.Errorf("Expected last ID 10 after reserving, got %d", last)
+			}
+			if tasks, _, _ := open(dir).Load(); len(tasks) != 1 {
+				t.Errorf("Expected reserving to keep the tasks, got %d", len(tasks))
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package task
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetArchive sets the repository that completed tasks are moved to by Archive.
+func (tm *TaskManager) SetArchive(archive Repository) {
+	tm.archive = archive
+}
+
+// Archive moves completed tasks out of the main list into the archive and returns
+// their IDs. When doneBefore is not zero, only tasks completed before it are moved.
+// Tasks in the trash stay where they are, and so does a task with a subtask that
+// cannot be archived with it, such as one still open, so no task in the main list
+// loses its parent. Archiving cannot be undone.
+func (tm *TaskManager) Archive(doneBefore time.Time) ([]int, error) {
+	if tm.archive == nil {
+		return nil, fmt.Errorf("no archive configured")
+	}
+
+	unlock, err := tm.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	tasks, rev, err := tm.repo.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	archivable := func(t Task) bool {
+		return t.IsDone() && !t.IsDeleted() && (doneBefore.IsZero() || t.completedAt().Before(doneBefore))
+	}
+
+	var moved []Task
+	kept := make([]Task, 0, len(tasks))
+	for _, t := range tasks {
+		if archivable(t) && allDescendants(tasks, t.ID, archivable) {
+			moved = append(moved, t)
+		} else {
+			kept = append(kept, t)
+		}
+	}
+	if len(moved) == 0 {
+		return nil, nil
+	}
+
+	archived, archiveRev, err := tm.archive.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load archive: %w", err)
+	}
+
+	// Write the archive first so a failure never loses tasks; replacing by ID
+	// keeps a retry after a failed save of the main list from duplicating them.
+	ids := make([]int, len(moved))
+	for i, t := range moved {
+		ids[i] = t.ID
+		if j := indexOfAny(archived, t.ID); j >= 0 {
+			archived[j] = t
+		} else {
+			archived = append(archived, t)
+		}
+	}
+	SortByID(archived)
+
+	if _, err := tm.archive.Save(archived, archiveRev); err != nil {
+		return nil, fmt.Errorf("failed to save archive: %w", err)
+	}
+	if _, err := tm.repo.Save(kept, rev); err != nil {
+		return nil, err
+	}
+
+	if tm.changeLog != nil {
+		now := time.Now()
+		changes := make([]Change, len(ids))
+		for i, id := range ids {
+			changes[i] = Change{TaskID: id, Time: now, Op: "archive", Kind: ChangeArchived, By: tm.actor}
+		}
+		if err := tm.changeLog.AppendChanges(changes); err != nil {
+			return nil, fmt.Errorf("failed to save change log: %w", err)
+		}
+	}
+
+	return ids, nil
+}
+
+// completedAt returns when a done task was completed. Tasks completed before
+// completion times were recorded fall back to their last update.
+func (t Task) completedAt() time.Time {
+	if t.CompletedAt != nil {
+		return *t.CompletedAt
+	}
+	return t.UpdatedAt
+}
+
+// Archived returns every task in the archive.
+func (tm *TaskManager) Archived() ([]Task, error) {
+	if tm.archive == nil {
+		return nil, fmt.Errorf("no archive configured")
+	}
+
+	archived, _, err := tm.archive.Load()
+	return archived, err
+}
+
+// newID returns the ID for a new task in tasks, skipping IDs used in the archive
+// so that archived tasks keep unique IDs, and IDs of purged tasks when the
+// repository remembers them.
+func (tm *TaskManager) newID(tasks []Task) (int, error) {
+	id := nextID(tasks)
+	if keeper, ok := tm.repo.(IDKeeper); ok {
+		last, err := keeper.LastID()
+		if err != nil {
+			return 0, err
+		}
+		id = max(id, last+1)
+	}
+	if tm.archive == nil {
+		return id, nil
+	}
+
+	archived, _, err := tm.archive.Load()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load archive: %w", err)
+	}
+	return max(id, nextID(archived)), nil
+}
@@ -0,0 +1,287 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChangeKind summarizes what an operation did to a task.
+type ChangeKind string
+
+const (
+	ChangeCreated   ChangeKind = "created"
+	ChangeUpdated   ChangeKind = "updated"
+	ChangeCompleted ChangeKind = "completed"
+	ChangeReopened  ChangeKind = "reopened"
+	ChangeDeleted   ChangeKind = "deleted"
+	ChangeRestored  ChangeKind = "restored"
+	ChangeArchived  ChangeKind = "archived"
+	ChangeRemoved   ChangeKind = "removed"
+)
+
+// FieldChange records one field of a task going from Old to New. Values are
+// rendered as text; an empty value means the field was unset.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+// Change records what one operation did to one task. Unlike the journal,
+// changes are kept for the lifetime of the task and are never undone; undoing
+// an operation records changes of its own.
+type Change struct {
+	TaskID int        `json:"task_id"`
+	Time   time.Time  `json:"time"`
+	Op     string     `json:"op"`
+	Kind   ChangeKind `json:"kind"`
+	// By is the user who ran the operation, if known.
+	By     string        `json:"by,omitempty"`
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// ChangeLog persists the per-task change history.
+type ChangeLog interface {
+	AppendChanges(changes []Change) error
+	LoadChanges(id int) ([]Change, error)
+}
+
+// TaskLog is the recorded lifecycle of a single task.
+type TaskLog struct {
+	// Task is the current state of the task, or nil if it has been purged.
+	Task *Task
+	// Archived reports whether Task was found in the archive.
+	Archived bool
+	// Changes lists the recorded changes, oldest first.
+	Changes []Change
+}
+
+// SetChangeLog enables recording of field-level changes to every task in l.
+// Without a change log, Log only reports a task's current state.
+func (tm *TaskManager) SetChangeLog(l ChangeLog) {
+	tm.changeLog = l
+}
+
+// SetActor sets the name recorded as the author of changes, e.g. the login name.
+func (tm *TaskManager) SetActor(name string) {
+	tm.actor = name
+}
+
+// Log returns the task with the given ID, wherever it is kept, together with its
+// recorded changes. Purged tasks are still found as long as changes were recorded.
+// The changes are looked up by ID, which relies on the repository being an
+// IDKeeper so that a new task never inherits the history of a purged one.
+func (tm *TaskManager) Log(id int) (TaskLog, error) {
+	var result TaskLog
+
+	tasks, _, err := tm.repo.Load()
+	if err != nil {
+		return result, err
+	}
+	if i := indexOfAny(tasks, id); i >= 0 {
+		result.Task = &tasks[i]
+	} else if tm.archive != nil {
+		archived, _, err := tm.archive.Load()
+		if err != nil {
+			return result, fmt.Errorf("failed to load archive: %w", err)
+		}
+		if i := indexOfAny(archived, id); i >= 0 {
+			result.Task = &archived[i]
+			result.Archived = true
+		}
+	}
+
+	if tm.changeLog != nil {
+		if result.Changes, err = tm.changeLog.LoadChanges(id); err != nil {
+			return result, fmt.Errorf("failed to load change log: %w", err)
+		}
+	}
+
+	if result.Task == nil && len(result.Changes) == 0 {
+		return result, fmt.Errorf("task with ID %d not found", id)
+	}
+	return result, nil
+}
+
+// logChanges appends a change for every task that differs between before and
+// after to the change log.
+func (tm *TaskManager) logChanges(op string, before, after []Task) error {
+	if tm.changeLog == nil {
+		return nil
+	}
+
+	now := time.Now()
+	var changes []Change
+	for _, id := range changedIDs(before, after) {
+		var b, a *Task
+		if i := indexOfAny(before, id); i >= 0 {
+			b = &before[i]
+		}
+		if i := indexOfAny(after, id); i >= 0 {
+			a = &after[i]
+		}
+		if b != nil && a != nil && sameTask(*b, *a) {
+			continue
+		}
+
+		changes = append(changes, Change{
+			TaskID: id,
+			Time:   now,
+			Op:     op,
+			Kind:   changeKind(b, a),
+			By:     tm.actor,
+			Fields: diffFields(b, a),
+		})
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if err := tm.changeLog.AppendChanges(changes); err != nil {
+		return fmt.Errorf("failed to save change log: %w", err)
+	}
+	return nil
+}
+
+// changedIDs returns the IDs found in either list, in order.
+func changedIDs(before, after []Task) []int {
+	seen := map[int]bool{}
+	var ids []int
+	for _, list := range [][]Task{before, after} {
+		for _, t := range list {
+			if !seen[t.ID] {
+				seen[t.ID] = true
+				ids = append(ids, t.ID)
+			}
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// changeKind classifies the change from before to after; either may be nil
+// for a task that was created or removed.
+func changeKind(before, after *Task) ChangeKind {
+	switch {
+	case before == nil:
+		return ChangeCreated
+	case after == nil:
+		return ChangeRemoved
+	case !before.IsDeleted() && after.IsDeleted():
+		return ChangeDeleted
+	case before.IsDeleted() && !after.IsDeleted():
+		return ChangeRestored
+	case !before.IsDone() && after.IsDone():
+		return ChangeCompleted
+	case !before.IsOpen() && after.IsOpen():
+		return ChangeReopened
+	}
+	return ChangeUpdated
+}
+
+// diffFields lists the user-visible fields that differ between before and
+// after. A nil task counts as having every field unset.
+func diffFields(before, after *Task) []FieldChange {
+	var b, a Task
+	if before != nil {
+		b = *before
+	}
+	if after != nil {
+		a = *after
+	}
+
+	var changes []FieldChange
+	add := func(field, old, new string) {
+		if old != new {
+			changes = append(changes, FieldChange{Field: field, Old: old, New: new})
+		}
+	}
+
+	add("description", b.Description, a.Description)
+	add("status", string(b.Status), string(a.Status))
+	add("priority", formatPriority(b.Priority), formatPriority(a.Priority))
+	add("due", formatDue(b.DueAt), formatDue(a.DueAt))
+	add("project", b.Project, a.Project)
+	add("tags", formatTags(b.Tags), formatTags(a.Tags))
+	add("parent", formatID(b.ParentID), formatID(a.ParentID))
+	add("depends_on", formatIDList(b.DependsOn), formatIDList(a.DependsOn))
+	add("recur", formatRecur(b.Recur), formatRecur(a.Recur))
+	add("estimate", formatEstimate(b.Estimate), formatEstimate(a.Estimate))
+	add("timer", formatTimer(b), formatTimer(a))
+	add("pomodoros", formatPomodoros(b), formatPomodoros(a))
+	return changes
+}
+
+func formatPriority(p Priority) string {
+	if p == PriorityNone {
+		return ""
+	}
+	return p.String()
+}
+
+func formatDue(due *time.Time) string {
+	if due == nil {
+		return ""
+	}
+	local := due.Local()
+	if local.Hour() == 0 && local.Minute() == 0 {
+		return local.Format("2006-01-02")
+	}
+	return local.Format("2006-01-02 15:04")
+}
+
+func formatTags(tags []string) string {
+	parts := make([]string, len(tags))
+	for i, tag := range tags {
+		parts[i] = "+" + tag
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatID(id int) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
+
+func formatIDList(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatRecur(r *Recurrence) string {
+	if r == nil {
+		return ""
+	}
+	return r.String()
+}
+
+func formatEstimate(e *Estimate) string {
+	if e == nil {
+		return ""
+	}
+	return e.String()
+}
+
+func formatTimer(t Task) string {
+	running, ok := t.Running()
+	if !ok {
+		return ""
+	}
+	return "running since " + running.Start.Local().Format("2006-01-02 15:04")
+}
+
+func formatPomodoros(t Task) string {
+	completed, interrupted := t.Pomodoros()
+	if completed+interrupted == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d completed, %d interrupted", completed, interrupted)
+}
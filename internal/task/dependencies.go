@@ -0,0 +1,198 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PlanStep is one open task in dependency order together with the open
+// prerequisites that still block it.
+type PlanStep struct {
+	Task      Task
+	BlockedBy []int
+}
+
+// AddDependencies records that the task with the given ID depends on each of the
+// prerequisite IDs. Unknown tasks, self-dependencies and cycles are rejected.
+func (tm *TaskManager) AddDependencies(id int, on ...int) error {
+	return tm.mutateIdempotent("depend", func(tasks []Task) ([]Task, error) {
+		i := indexOf(tasks, id)
+		if i < 0 {
+			return nil, fmt.Errorf("task with ID %d not found", id)
+		}
+
+		for _, dep := range on {
+			if dep == id {
+				return nil, fmt.Errorf("task %d cannot depend on itself", id)
+			}
+			if indexOf(tasks, dep) < 0 {
+				return nil, fmt.Errorf("task with ID %d not found", dep)
+			}
+			if dependsOn(tasks, dep, id) {
+				return nil, fmt.Errorf("task %d already depends on %d; adding this dependency would create a cycle", dep, id)
+			}
+			if !containsID(tasks[i].DependsOn, dep) {
+				tasks[i].DependsOn = append(tasks[i].DependsOn, dep)
+			}
+		}
+		tasks[i].UpdatedAt = time.Now()
+
+		return tasks, nil
+	})
+}
+
+// RemoveDependencies drops the given prerequisites from the task with the given ID.
+func (tm *TaskManager) RemoveDependencies(id int, on ...int) error {
+	return tm.mutateIdempotent("undepend", func(tasks []Task) ([]Task, error) {
+		i := indexOf(tasks, id)
+		if i < 0 {
+			return nil, fmt.Errorf("task with ID %d not found", id)
+		}
+
+		tasks[i].DependsOn = removeIDs(tasks[i].DependsOn, on)
+		tasks[i].UpdatedAt = time.Now()
+
+		return tasks, nil
+	})
+}
+
+// Plan returns every open task in topological order, so that prerequisites come
+// before the tasks that depend on them. Among tasks that are ready at the same
+// point, the more urgent one comes first. Tasks in the trash are left out, but
+// still block the tasks that depend on them.
+func (tm *TaskManager) Plan() ([]PlanStep, error) {
+	tasks, err := tm.loadOpen()
+	if err != nil {
+		return nil, err
+	}
+
+	open := map[int]Task{}
+	for _, t := range tasks {
+		if !t.IsDeleted() {
+			open[t.ID] = t
+		}
+	}
+
+	// Kahn's algorithm over open tasks; closed prerequisites are already satisfied
+	remaining := map[int]int{}
+	dependents := map[int][]int{}
+	var ready []Task
+	for _, t := range open {
+		for _, dep := range t.DependsOn {
+			if _, ok := open[dep]; ok {
+				remaining[t.ID]++
+				dependents[dep] = append(dependents[dep], t.ID)
+			}
+		}
+		if remaining[t.ID] == 0 {
+			ready = append(ready, t)
+		}
+	}
+
+	var plan []PlanStep
+	for len(ready) > 0 {
+		SortByUrgency(ready)
+		next := ready[0]
+		ready = ready[1:]
+
+		plan = append(plan, PlanStep{Task: next, BlockedBy: openPrerequisites(tasks, next)})
+		for _, d := range dependents[next.ID] {
+			remaining[d]--
+			if remaining[d] == 0 {
+				ready = append(ready, open[d])
+			}
+		}
+	}
+
+	if len(plan) != len(open) {
+		return nil, fmt.Errorf("dependency cycle detected among open tasks")
+	}
+
+	return plan, nil
+}
+
+// Next returns the open tasks that are actionable right now: not blocked or waiting,
+// with no open prerequisites, ranked by priority and due date.
+func (tm *TaskManager) Next() ([]Task, error) {
+	plan, err := tm.Plan()
+	if err != nil {
+		return nil, err
+	}
+
+	var actionable []Task
+	for _, step := range plan {
+		status := step.Task.Status
+		if len(step.BlockedBy) == 0 && status != StatusBlocked && status != StatusWaiting {
+			actionable = append(actionable, step.Task)
+		}
+	}
+	SortByUrgency(actionable)
+
+	return actionable, nil
+}
+
+// openPrerequisites returns the IDs of t's prerequisites that are still open.
+// A prerequisite in the trash still counts, since it can be restored; purging
+// it removes the dependency.
+func openPrerequisites(tasks []Task, t Task) []int {
+	var ids []int
+	for _, dep := range t.DependsOn {
+		if i := indexOfAny(tasks, dep); i >= 0 && tasks[i].IsOpen() {
+			ids = append(ids, dep)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// dependsOn reports whether from depends on target, directly or transitively.
+func dependsOn(tasks []Task, from, target int) bool {
+	seen := map[int]bool{}
+	stack := []int{from}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if current == target {
+			return true
+		}
+		if seen[current] {
+			continue
+		}
+		seen[current] = true
+
+		if i := indexOf(tasks, current); i >= 0 {
+			stack = append(stack, tasks[i].DependsOn...)
+		}
+	}
+	return false
+}
+
+// FormatIDs renders task IDs as a comma-separated list.
+func FormatIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func containsID(ids []int, id int) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
+
+func removeIDs(ids, remove []int) []int {
+	var kept []int
+	for _, id := range ids {
+		if !containsID(remove, id) {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
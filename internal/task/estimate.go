@@ -0,0 +1,236 @@
+package task
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Estimate is the expected effort for a task, either as a duration of work or
+// in story points. It is stored in its textual form, e.g. "3h" or "5pt".
+type Estimate struct {
+	Duration time.Duration
+	Points   float64
+}
+
+// ParseEstimate parses a duration such as "3h", "45m" or "1h30m", or story
+// points such as "5pt", "2.5pts" or "3sp". A bare number is rejected, since it
+// could mean either.
+func ParseEstimate(s string) (*Estimate, error) {
+	input := s
+	s = strings.ToLower(strings.ReplaceAll(s, " ", ""))
+	if s == "" {
+		return nil, fmt.Errorf("empty estimate")
+	}
+	number, unit := s, ""
+	for _, suffix := range []string{"points", "point", "pts", "pt", "sp"} {
+		if trimmed, ok := strings.CutSuffix(s, suffix); ok {
+			number, unit = trimmed, suffix
+			break
+		}
+	}
+	if points, err := strconv.ParseFloat(number, 64); err == nil {
+		if unit == "" {
+			return nil, fmt.Errorf("invalid estimate %q: add a unit, e.g. 90m for minutes or 5pt for points", input)
+		}
+		if !(points > 0) || math.IsInf(points, 0) {
+			return nil, fmt.Errorf("invalid estimate %q: points must be positive", input)
+		}
+		return &Estimate{Points: points}, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid estimate %q (use a duration like 3h or 90m, or points like 5pt)", input)
+	}
+	if d < time.Minute {
+		return nil, fmt.Errorf("invalid estimate %q: must be at least a minute", input)
+	}
+	return &Estimate{Duration: d.Round(time.Minute)}, nil
+}
+
+// IsPoints reports whether the estimate is given in story points.
+func (e Estimate) IsPoints() bool {
+	return e.Points > 0
+}
+
+func (e Estimate) String() string {
+	if e.IsPoints() {
+		return strconv.FormatFloat(e.Points, 'f', -1, 64) + "pt"
+	}
+
+	h := int(e.Duration / time.Hour)
+	m := int(e.Duration % time.Hour / time.Minute)
+	switch {
+	case h == 0:
+		return fmt.Sprintf("%dm", m)
+	case m == 0:
+		return fmt.Sprintf("%dh", h)
+	}
+	return fmt.Sprintf("%dh%dm", h, m)
+}
+
+func (e Estimate) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+func (e *Estimate) UnmarshalText(text []byte) error {
+	parsed, err := ParseEstimate(string(text))
+	if err != nil {
+		return err
+	}
+	*e = *parsed
+	return nil
+}
+
+// Effort measures for EstimateOptions.Against.
+const (
+	AgainstTracked = "tracked"
+	AgainstCycle   = "cycle"
+)
+
+// EstimateOptions controls Estimates.
+type EstimateOptions struct {
+	// Against measures the actual effort as AgainstTracked time (the default)
+	// or as AgainstCycle time, from when work started until completion.
+	Against string
+	// Threshold flags tasks that overran their estimate by more than this
+	// fraction, e.g. 0.5 for tasks that took over 50% longer than estimated.
+	Threshold float64
+}
+
+// EstimateResult compares the estimate of a completed task with its actual effort.
+type EstimateResult struct {
+	Task   Task
+	Actual time.Duration
+	// Ratio is the actual effort over the estimated effort: 1 is spot on, 2
+	// means the task took twice as long as estimated.
+	Ratio float64
+}
+
+// EstimateAccuracy summarizes how well the tasks of one project were estimated.
+type EstimateAccuracy struct {
+	// Project is empty for tasks without a project.
+	Project string
+	// Tasks counts the tasks estimated as a duration.
+	Tasks int
+	// Ratio is the total actual effort over the total estimated effort of
+	// those tasks, or 0 if there are none.
+	Ratio float64
+	// PointTasks counts the tasks estimated in story points.
+	PointTasks int
+	// PerPoint is the median time a story point took, or 0 if no task of
+	// the project was estimated in points.
+	PerPoint time.Duration
+}
+
+// EstimateReport is the outcome of comparing estimates with actual effort.
+type EstimateReport struct {
+	// Projects is sorted by project.
+	Projects []EstimateAccuracy
+	// Overruns lists the tasks estimated as a duration that exceeded the
+	// threshold, worst first.
+	Overruns []EstimateResult
+}
+
+// Estimates compares the estimates of completed tasks, including archived
+// ones, with the effort they actually took. Story points have no fixed length,
+// so there is nothing to hold a point estimate against; instead of being
+// flagged as overruns, point tasks are summarized by the median time a point
+// took in their project. Tasks without an estimate or without a measurable
+// effort are left out.
+func (tm *TaskManager) Estimates(opts EstimateOptions) (EstimateReport, error) {
+	var report EstimateReport
+
+	if opts.Against == "" {
+		opts.Against = AgainstTracked
+	}
+	if opts.Against != AgainstTracked && opts.Against != AgainstCycle {
+		return report, fmt.Errorf("invalid effort measure %q (use %s or %s)", opts.Against, AgainstTracked, AgainstCycle)
+	}
+	if opts.Threshold < 0 {
+		return report, fmt.Errorf("threshold cannot be negative")
+	}
+
+	tasks, err := tm.loadLive()
+	if err != nil {
+		return report, err
+	}
+	if tm.archive != nil {
+		archived, err := tm.Archived()
+		if err != nil {
+			return report, err
+		}
+		tasks = append(tasks, archived...)
+	}
+
+	now := time.Now()
+	byProject := map[string][]EstimateResult{}
+	for _, t := range tasks {
+		if !t.IsDone() || t.Estimate == nil {
+			continue
+		}
+		actual := actualEffort(t, opts.Against, now)
+		if actual <= 0 {
+			continue
+		}
+		byProject[t.Project] = append(byProject[t.Project], EstimateResult{Task: t, Actual: actual})
+	}
+
+	for project, results := range byProject {
+		accuracy := EstimateAccuracy{Project: project}
+
+		var perPoint []time.Duration
+		var actual, estimated time.Duration
+		for i := range results {
+			r := &results[i]
+			if r.Task.Estimate.IsPoints() {
+				accuracy.PointTasks++
+				perPoint = append(perPoint, time.Duration(float64(r.Actual)/r.Task.Estimate.Points))
+				continue
+			}
+
+			accuracy.Tasks++
+			r.Ratio = float64(r.Actual) / float64(r.Task.Estimate.Duration)
+			actual += r.Actual
+			estimated += r.Task.Estimate.Duration
+
+			if r.Ratio > 1+opts.Threshold {
+				report.Overruns = append(report.Overruns, *r)
+			}
+		}
+		if estimated > 0 {
+			accuracy.Ratio = float64(actual) / float64(estimated)
+		}
+		accuracy.PerPoint = distribution(perPoint).Median
+
+		report.Projects = append(report.Projects, accuracy)
+	}
+
+	sort.Slice(report.Projects, func(i, j int) bool {
+		return report.Projects[i].Project < report.Projects[j].Project
+	})
+	sort.Slice(report.Overruns, func(i, j int) bool {
+		a, b := report.Overruns[i], report.Overruns[j]
+		if a.Ratio != b.Ratio {
+			return a.Ratio > b.Ratio
+		}
+		return a.Task.ID < b.Task.ID
+	})
+
+	return report, nil
+}
+
+// actualEffort measures the effort a completed task took, or 0 if it is unknown.
+func actualEffort(t Task, against string, now time.Time) time.Duration {
+	if against == AgainstCycle {
+		if t.StartedAt == nil || t.CompletedAt == nil {
+			return 0
+		}
+		return nonNegative(t.CompletedAt.Sub(*t.StartedAt))
+	}
+	return t.TrackedTime(now)
+}
@@ -0,0 +1,165 @@
+package task
+
+import "time"
+
+// Filter selects a subset of tasks. The zero value matches every task.
+type Filter struct {
+	// Overdue keeps only open tasks whose due date has passed.
+	Overdue bool
+	// DueBefore keeps only tasks due strictly before the given time.
+	DueBefore *time.Time
+	// IncludeTags keeps only tasks carrying all of these tags.
+	IncludeTags []string
+	// ExcludeTags drops tasks carrying any of these tags.
+	ExcludeTags []string
+	// Project keeps only tasks in this project or its sub-projects.
+	Project string
+	// Where keeps only tasks matching a query expression.
+	Where Matcher
+	// Now is the reference time for date-based criteria; the zero value means time.Now().
+	Now time.Time
+}
+
+// Matcher is a predicate over tasks, such as a parsed query.
+type Matcher interface {
+	Match(t Task) bool
+}
+
+// Match reports whether the task satisfies every criterion of the filter.
+func (f Filter) Match(t Task) bool {
+	now := f.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	if f.Overdue && !t.IsOverdue(now) {
+		return false
+	}
+
+	if f.DueBefore != nil && (t.DueAt == nil || !t.DueAt.Before(*f.DueBefore)) {
+		return false
+	}
+
+	if f.Project != "" && !t.InProject(f.Project) {
+		return false
+	}
+
+	for _, tag := range f.IncludeTags {
+		if !t.HasTag(tag) {
+			return false
+		}
+	}
+
+	for _, tag := range f.ExcludeTags {
+		if t.HasTag(tag) {
+			return false
+		}
+	}
+
+	if f.Where != nil && !f.Where.Match(t) {
+		return false
+	}
+
+	return true
+}
+
+// FilterTasks returns the tasks that match f, preserving their order.
+func FilterTasks(tasks []Task, f Filter) []Task {
+	var matched []Task
+	for _, t := range tasks {
+		if f.Match(t) {
+			matched = append(matched, t)
+		}
+	}
+	return matched
+}
+
+// Query selects tasks by the fields that a repository may index.
+// Empty fields match every task.
+type Query struct {
+	// Statuses keeps tasks in any of these statuses.
+	Statuses []TaskStatus
+	// DueBefore keeps tasks due strictly before the given time.
+	DueBefore *time.Time
+	// Tags keeps tasks carrying all of these tags.
+	Tags []string
+}
+
+// Querier is implemented by repositories that can look tasks up through
+// indexes instead of loading all of them. Results include tasks in the trash.
+type Querier interface {
+	Query(q Query) ([]Task, error)
+}
+
+// Find returns the tasks outside the trash that match f. When the repository
+// supports it, the filter's indexed criteria are answered by a Query.
+func (tm *TaskManager) Find(f Filter) ([]Task, error) {
+	querier, ok := tm.repo.(Querier)
+	if !ok {
+		tasks, err := tm.loadLive()
+		if err != nil {
+			return nil, err
+		}
+		return FilterTasks(tasks, f), nil
+	}
+
+	q := Query{Tags: f.IncludeTags, DueBefore: f.DueBefore}
+	if f.Overdue {
+		now := f.Now
+		if now.IsZero() {
+			now = time.Now()
+		}
+		today := startOfDay(now)
+		if q.DueBefore == nil || today.Before(*q.DueBefore) {
+			q.DueBefore = &today
+		}
+		q.Statuses = openStatuses()
+	}
+
+	tasks, err := querier.Query(q)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []Task
+	for _, t := range tasks {
+		if !t.IsDeleted() && f.Match(t) {
+			found = append(found, t)
+		}
+	}
+	return found, nil
+}
+
+// loadOpen loads the open tasks, including those in the trash, through an index
+// when the repository has one.
+func (tm *TaskManager) loadOpen() ([]Task, error) {
+	var tasks []Task
+	var err error
+	if querier, ok := tm.repo.(Querier); ok {
+		tasks, err = querier.Query(Query{Statuses: openStatuses()})
+	} else {
+		tasks, _, err = tm.repo.Load()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var open []Task
+	for _, t := range tasks {
+		if t.IsOpen() {
+			open = append(open, t)
+		}
+	}
+	return open, nil
+}
+
+// openStatuses lists every status of a task that still needs work.
+func openStatuses() []TaskStatus {
+	var open []TaskStatus
+	for _, status := range Statuses {
+		if (Task{Status: status}).IsOpen() {
+			open = append(open, status)
+		}
+	}
+	return open
+}
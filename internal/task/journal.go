@@ -0,0 +1,379 @@
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// maxJournalEntries bounds how many operations are kept for undo.
+const maxJournalEntries = 200
+
+// JournalEntry records one mutating operation as the state of every task it
+// touched before and after. A task missing from Before was created by the
+// operation; a task missing from After was removed by it.
+type JournalEntry struct {
+	Seq    int       `json:"seq"`
+	Time   time.Time `json:"time"`
+	Op     string    `json:"op"`
+	Before []Task    `json:"before,omitempty"`
+	After  []Task    `json:"after,omitempty"`
+	// Undone marks operations that have been undone and can be redone.
+	Undone bool `json:"undone,omitempty"`
+}
+
+// TaskIDs returns the IDs of the tasks touched by the operation.
+func (e JournalEntry) TaskIDs() []int {
+	seen := map[int]bool{}
+	var ids []int
+	for _, list := range [][]Task{e.Before, e.After} {
+		for _, t := range list {
+			if !seen[t.ID] {
+				seen[t.ID] = true
+				ids = append(ids, t.ID)
+			}
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Journal persists the operation history used for undo and redo.
+type Journal interface {
+	LoadJournal() ([]JournalEntry, error)
+	SaveJournal(entries []JournalEntry) error
+}
+
+// SetJournal enables recording of every mutating operation in j.
+// Without a journal, operations are not recorded and cannot be undone.
+func (tm *TaskManager) SetJournal(j Journal) {
+	tm.journal = j
+}
+
+// mutate loads the tasks, applies fn and saves the result while holding the
+// repository lock. When a journal is set, the tasks that fn changed are
+// recorded under op so they can be undone. If the tasks were modified
+// concurrently, nothing is saved and the error wraps ErrConflict.
+func (tm *TaskManager) mutate(op string, fn func(tasks []Task) ([]Task, error)) error {
+	unlock, err := tm.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	tasks, rev, err := tm.repo.Load()
+	if err != nil {
+		return err
+	}
+
+	before := cloneTasks(tasks)
+
+	tasks, err = fn(tasks)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tm.repo.Save(tasks, rev); err != nil {
+		return err
+	}
+
+	if err := tm.logChanges(op, before, tasks); err != nil {
+		return err
+	}
+	return tm.record(op, before, tasks)
+}
+
+// mutateIdempotent is like mutate but retries after a conflicting concurrent
+// modification. Use it only when fn sets absolute state, so that running it
+// again on the newer tasks still gives the intended result.
+func (tm *TaskManager) mutateIdempotent(op string, fn func(tasks []Task) ([]Task, error)) error {
+	var err error
+	for attempt := 0; attempt <= maxConflictRetries; attempt++ {
+		if err = tm.mutate(op, fn); !errors.Is(err, ErrConflict) {
+			return err
+		}
+	}
+	return err
+}
+
+// record appends the difference between two task lists to the journal.
+// Recording a new operation discards anything that could have been redone.
+func (tm *TaskManager) record(op string, before, after []Task) error {
+	if tm.journal == nil {
+		return nil
+	}
+
+	changedBefore, changedAfter := diffTasks(before, after)
+	if len(changedBefore) == 0 && len(changedAfter) == 0 {
+		return nil
+	}
+
+	entries, err := tm.journal.LoadJournal()
+	if err != nil {
+		return fmt.Errorf("failed to load journal: %w", err)
+	}
+
+	seq := 1
+	if len(entries) > 0 {
+		seq = entries[len(entries)-1].Seq + 1
+	}
+
+	kept := entries[:0]
+	for _, e := range entries {
+		if !e.Undone {
+			kept = append(kept, e)
+		}
+	}
+	entries = append(kept, JournalEntry{
+		Seq:    seq,
+		Time:   time.Now(),
+		Op:     op,
+		Before: changedBefore,
+		After:  changedAfter,
+	})
+	if len(entries) > maxJournalEntries {
+		entries = entries[len(entries)-maxJournalEntries:]
+	}
+
+	if err := tm.journal.SaveJournal(entries); err != nil {
+		return fmt.Errorf("failed to save journal: %w", err)
+	}
+	return nil
+}
+
+// Undo reverts the last n operations that have not been undone yet, newest first,
+// and returns them.
+func (tm *TaskManager) Undo(n int) ([]JournalEntry, error) {
+	return tm.replay(n, true)
+}
+
+// Redo re-applies the last n undone operations, oldest first, and returns them.
+func (tm *TaskManager) Redo(n int) ([]JournalEntry, error) {
+	return tm.replay(n, false)
+}
+
+// History returns up to limit of the most recent journal entries, newest first.
+// A limit of 0 or less returns all of them.
+func (tm *TaskManager) History(limit int) ([]JournalEntry, error) {
+	if tm.journal == nil {
+		return nil, fmt.Errorf("no journal configured")
+	}
+
+	entries, err := tm.journal.LoadJournal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load journal: %w", err)
+	}
+
+	var history []JournalEntry
+	for i := len(entries) - 1; i >= 0 && (limit <= 0 || len(history) < limit); i-- {
+		history = append(history, entries[i])
+	}
+	return history, nil
+}
+
+// replay undoes (or redoes) up to n journal entries. It refuses to touch tasks
+// that were changed outside the journal since the entry was recorded.
+func (tm *TaskManager) replay(n int, undo bool) ([]JournalEntry, error) {
+	if tm.journal == nil {
+		return nil, fmt.Errorf("no journal configured")
+	}
+	if n < 1 {
+		return nil, fmt.Errorf("count must be at least 1")
+	}
+
+	unlock, err := tm.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	entries, err := tm.journal.LoadJournal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load journal: %w", err)
+	}
+
+	tasks, rev, err := tm.repo.Load()
+	if err != nil {
+		return nil, err
+	}
+	before := cloneTasks(tasks)
+
+	var replayed []JournalEntry
+	for len(replayed) < n {
+		i := nextReplayable(entries, undo)
+		if i < 0 {
+			break
+		}
+
+		e := entries[i]
+		expected, target := e.After, e.Before
+		if !undo {
+			expected, target = e.Before, e.After
+		}
+		if err := checkUnchanged(tasks, e.TaskIDs(), expected); err != nil {
+			if len(replayed) == 0 {
+				return nil, fmt.Errorf("cannot %s %q: %w", replayVerb(undo), e.Op, err)
+			}
+			break
+		}
+
+		tasks = applyState(tasks, e.TaskIDs(), target)
+		entries[i].Undone = undo
+		replayed = append(replayed, entries[i])
+	}
+
+	if len(replayed) == 0 {
+		return nil, fmt.Errorf("nothing to %s", replayVerb(undo))
+	}
+
+	if _, err := tm.repo.Save(tasks, rev); err != nil {
+		return nil, err
+	}
+	if err := tm.logChanges(replayVerb(undo), before, tasks); err != nil {
+		return nil, err
+	}
+	if err := tm.journal.SaveJournal(entries); err != nil {
+		return nil, fmt.Errorf("failed to save journal: %w", err)
+	}
+
+	return replayed, nil
+}
+
+// nextReplayable finds the newest applied entry when undoing, or the oldest
+// undone entry when redoing. It returns -1 if there is none.
+func nextReplayable(entries []JournalEntry, undo bool) int {
+	if undo {
+		for i := len(entries) - 1; i >= 0; i-- {
+			if !entries[i].Undone {
+				return i
+			}
+		}
+		return -1
+	}
+	for i, e := range entries {
+		if e.Undone {
+			return i
+		}
+	}
+	return -1
+}
+
+func replayVerb(undo bool) string {
+	if undo {
+		return "undo"
+	}
+	return "redo"
+}
+
+// checkUnchanged verifies that the tasks with the given IDs are exactly as
+// recorded in expected (absent from expected means absent from tasks).
+func checkUnchanged(tasks []Task, ids []int, expected []Task) error {
+	for _, id := range ids {
+		i := indexOfAny(tasks, id)
+		j := indexOfAny(expected, id)
+		switch {
+		case i < 0 && j < 0:
+		case i < 0 || j < 0 || !sameTask(tasks[i], expected[j]):
+			return fmt.Errorf("task %d has changed since", id)
+		}
+	}
+	return nil
+}
+
+// applyState sets the tasks with the given IDs to their state in target,
+// removing those that target does not contain and keeping the list ordered by ID.
+func applyState(tasks []Task, ids []int, target []Task) []Task {
+	for _, id := range ids {
+		i := indexOfAny(tasks, id)
+		j := indexOfAny(target, id)
+		switch {
+		case j >= 0 && i >= 0:
+			tasks[i] = target[j].Clone()
+		case j >= 0:
+			tasks = append(tasks, target[j].Clone())
+		case i >= 0:
+			tasks = append(tasks[:i], tasks[i+1:]...)
+		}
+	}
+	SortByID(tasks)
+	return tasks
+}
+
+// diffTasks returns the before and after state of every task that differs
+// between two lists.
+func diffTasks(before, after []Task) (changedBefore, changedAfter []Task) {
+	for _, b := range before {
+		j := indexOfAny(after, b.ID)
+		if j < 0 || !sameTask(b, after[j]) {
+			changedBefore = append(changedBefore, b.Clone())
+		}
+	}
+	for _, a := range after {
+		i := indexOfAny(before, a.ID)
+		if i < 0 || !sameTask(before[i], a) {
+			changedAfter = append(changedAfter, a.Clone())
+		}
+	}
+	return changedBefore, changedAfter
+}
+
+// sameTask compares tasks by their stored form, so differences that do not
+// survive a round trip through storage (monotonic clock readings, nil versus
+// empty slices) are ignored.
+func sameTask(a, b Task) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
+
+// Clone returns a deep copy of the task.
+func (t Task) Clone() Task {
+	c := t
+	if t.DueAt != nil {
+		due := *t.DueAt
+		c.DueAt = &due
+	}
+	if t.DeletedAt != nil {
+		deleted := *t.DeletedAt
+		c.DeletedAt = &deleted
+	}
+	if t.StartedAt != nil {
+		started := *t.StartedAt
+		c.StartedAt = &started
+	}
+	if t.CompletedAt != nil {
+		completed := *t.CompletedAt
+		c.CompletedAt = &completed
+	}
+	if t.Recur != nil {
+		recur := *t.Recur
+		recur.Weekdays = append([]time.Weekday(nil), t.Recur.Weekdays...)
+		c.Recur = &recur
+	}
+	if t.Estimate != nil {
+		estimate := *t.Estimate
+		c.Estimate = &estimate
+	}
+	c.Tags = append([]string(nil), t.Tags...)
+	c.DependsOn = append([]int(nil), t.DependsOn...)
+	c.Tracked = nil
+	for _, iv := range t.Tracked {
+		if iv.End != nil {
+			end := *iv.End
+			iv.End = &end
+		}
+		c.Tracked = append(c.Tracked, iv)
+	}
+	return c
+}
+
+func cloneTasks(tasks []Task) []Task {
+	clones := make([]Task, len(tasks))
+	for i, t := range tasks {
+		clones[i] = t.Clone()
+	}
+	return clones
+}
@@ -0,0 +1,122 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ProjectNode is one level of the project hierarchy. Counts include all sub-projects;
+// cancelled tasks are counted in neither Open nor Done.
+type ProjectNode struct {
+	Name     string // last segment, e.g. "auth"
+	Path     string // full dotted name, e.g. "work.api.auth"
+	Open     int
+	Done     int
+	Children []*ProjectNode
+}
+
+// PercentDone returns the share of completed tasks in the node, from 0 to 100.
+func (n *ProjectNode) PercentDone() float64 {
+	total := n.Open + n.Done
+	if total == 0 {
+		return 0
+	}
+	return float64(n.Done) * 100 / float64(total)
+}
+
+// NormalizeProject validates a dotted project name such as "work.api.auth"
+// and lowercases it. An empty name means "no project".
+func NormalizeProject(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return "", nil
+	}
+	for _, segment := range strings.Split(name, ".") {
+		if segment == "" || strings.ContainsAny(segment, " \t\n") {
+			return "", fmt.Errorf("invalid project name %q", name)
+		}
+	}
+	return name, nil
+}
+
+// InProject reports whether the task belongs to the project or one of its sub-projects.
+func (t Task) InProject(project string) bool {
+	return t.Project == project || strings.HasPrefix(t.Project, project+".")
+}
+
+// Move assigns the task with the given ID to a project. An empty project removes it from any project.
+func (tm *TaskManager) Move(id int, project string) error {
+	project, err := NormalizeProject(project)
+	if err != nil {
+		return err
+	}
+
+	return tm.mutateIdempotent("move", func(tasks []Task) ([]Task, error) {
+		i := indexOf(tasks, id)
+		if i < 0 {
+			return nil, fmt.Errorf("task with ID %d not found", id)
+		}
+
+		tasks[i].Project = project
+		tasks[i].UpdatedAt = time.Now()
+
+		return tasks, nil
+	})
+}
+
+// Projects builds the project hierarchy with open/done counts, sorted by name at each level.
+// Tasks without a project are not included.
+func (tm *TaskManager) Projects() ([]*ProjectNode, error) {
+	tasks, err := tm.loadLive()
+	if err != nil {
+		return nil, err
+	}
+
+	root := &ProjectNode{}
+	nodes := map[string]*ProjectNode{}
+
+	for _, t := range tasks {
+		if t.Project == "" {
+			continue
+		}
+
+		parent := root
+		path := ""
+		for _, segment := range strings.Split(t.Project, ".") {
+			if path == "" {
+				path = segment
+			} else {
+				path += "." + segment
+			}
+
+			node, ok := nodes[path]
+			if !ok {
+				node = &ProjectNode{Name: segment, Path: path}
+				nodes[path] = node
+				parent.Children = append(parent.Children, node)
+			}
+
+			switch {
+			case t.IsDone():
+				node.Done++
+			case t.IsOpen():
+				node.Open++
+			}
+			parent = node
+		}
+	}
+
+	sortProjectNodes(root.Children)
+	return root.Children, nil
+}
+
+func sortProjectNodes(nodes []*ProjectNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+	for _, n := range nodes {
+		sortProjectNodes(n.Children)
+	}
+}
@@ -0,0 +1,346 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the base unit of a recurrence rule.
+type Frequency string
+
+const (
+	Daily   Frequency = "daily"
+	Weekly  Frequency = "weekly"
+	Monthly Frequency = "monthly"
+	Yearly  Frequency = "yearly"
+)
+
+var frequencyUnits = map[Frequency]string{
+	Daily:   "day",
+	Weekly:  "week",
+	Monthly: "month",
+	Yearly:  "year",
+}
+
+// Recurrence is an RRULE-style repetition rule: every Interval units of Frequency,
+// optionally pinned to specific weekdays (weekly) or a day of the month (monthly
+// and yearly). It is stored in its textual form, e.g. "weekly:fri" or "every 2 weeks".
+type Recurrence struct {
+	Frequency Frequency
+	Interval  int
+	Weekdays  []time.Weekday
+	MonthDay  int
+}
+
+// ParseRecurrence parses rules such as "daily", "weekly:fri", "weekly:mon,thu",
+// "monthly:15", "weekdays", "every 2 weeks", "every fri" or "every 3 months:1".
+func ParseRecurrence(s string) (*Recurrence, error) {
+	input := s
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	if s == "" {
+		return nil, fmt.Errorf("empty recurrence rule")
+	}
+
+	if s == "weekdays" {
+		return &Recurrence{Frequency: Weekly, Interval: 1, Weekdays: []time.Weekday{
+			time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday,
+		}}, nil
+	}
+
+	base, spec, hasSpec := strings.Cut(s, ":")
+	r := &Recurrence{Interval: 1}
+
+	if rest, ok := strings.CutPrefix(base, "every "); ok {
+		fields := strings.Fields(rest)
+		if len(fields) == 2 {
+			n, err := strconv.Atoi(fields[0])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid interval in recurrence %q", input)
+			}
+			r.Interval = n
+			rest = fields[1]
+		}
+		unit := strings.TrimSuffix(rest, "s")
+		for freq, name := range frequencyUnits {
+			if unit == name {
+				r.Frequency = freq
+			}
+		}
+		if r.Frequency == "" && r.Interval == 1 && !hasSpec {
+			if day, ok := parseWeekday(rest); ok {
+				r.Frequency = Weekly
+				r.Weekdays = []time.Weekday{day}
+				return r, nil
+			}
+		}
+	} else {
+		r.Frequency = Frequency(base)
+	}
+
+	if _, ok := frequencyUnits[r.Frequency]; !ok {
+		return nil, fmt.Errorf("invalid recurrence %q (try daily, weekly:fri, monthly:15 or \"every 2 weeks\")", input)
+	}
+
+	if hasSpec {
+		switch r.Frequency {
+		case Weekly:
+			for _, name := range strings.Split(spec, ",") {
+				day, ok := parseWeekday(strings.TrimSpace(name))
+				if !ok {
+					return nil, fmt.Errorf("invalid weekday %q in recurrence %q", name, input)
+				}
+				r.Weekdays = append(r.Weekdays, day)
+			}
+			sort.Slice(r.Weekdays, func(i, j int) bool {
+				return weekdayIndex(r.Weekdays[i]) < weekdayIndex(r.Weekdays[j])
+			})
+		case Monthly, Yearly:
+			day, err := strconv.Atoi(spec)
+			if err != nil || day < 1 || day > 31 {
+				return nil, fmt.Errorf("invalid day of month %q in recurrence %q", spec, input)
+			}
+			r.MonthDay = day
+		default:
+			return nil, fmt.Errorf("%s recurrences do not take a %q qualifier", r.Frequency, spec)
+		}
+	}
+
+	return r, nil
+}
+
+// String renders the rule in the canonical form accepted by ParseRecurrence.
+func (r Recurrence) String() string {
+	var s string
+	if r.Interval <= 1 {
+		s = string(r.Frequency)
+	} else {
+		s = fmt.Sprintf("every %d %ss", r.Interval, frequencyUnits[r.Frequency])
+	}
+
+	switch {
+	case len(r.Weekdays) > 0:
+		names := make([]string, len(r.Weekdays))
+		for i, day := range r.Weekdays {
+			names[i] = strings.ToLower(day.String()[:3])
+		}
+		s += ":" + strings.Join(names, ",")
+	case r.MonthDay > 0:
+		s += ":" + strconv.Itoa(r.MonthDay)
+	}
+	return s
+}
+
+func (r Recurrence) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Recurrence) UnmarshalText(text []byte) error {
+	parsed, err := ParseRecurrence(string(text))
+	if err != nil {
+		return err
+	}
+	*r = *parsed
+	return nil
+}
+
+// First returns the first occurrence on or after the day of from.
+func (r Recurrence) First(from time.Time) time.Time {
+	day := startOfDay(from)
+	switch {
+	case len(r.Weekdays) > 0:
+		for i := 0; i < 7; i++ {
+			if r.onWeekday(day.AddDate(0, 0, i)) {
+				return day.AddDate(0, 0, i)
+			}
+		}
+	case r.MonthDay > 0:
+		if day.Day() <= r.MonthDay {
+			return monthDay(day.Year(), day.Month(), r.MonthDay, day.Location())
+		}
+		return monthDay(day.Year(), day.Month()+1, r.MonthDay, day.Location())
+	}
+	return day
+}
+
+// Next returns the occurrence that follows the one on the day of from. Monthly
+// and yearly rules without a day of the month keep the day of from; see anchored.
+func (r Recurrence) Next(from time.Time) time.Time {
+	day := startOfDay(from)
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	switch r.Frequency {
+	case Daily:
+		return day.AddDate(0, 0, interval)
+	case Weekly:
+		if len(r.Weekdays) == 0 {
+			return day.AddDate(0, 0, 7*interval)
+		}
+		// Later days in the same week first, then the first day of the next active week
+		monday := day.AddDate(0, 0, -weekdayIndex(day.Weekday()))
+		for d := day.AddDate(0, 0, 1); d.Before(monday.AddDate(0, 0, 7)); d = d.AddDate(0, 0, 1) {
+			if r.onWeekday(d) {
+				return d
+			}
+		}
+		nextWeek := monday.AddDate(0, 0, 7*interval)
+		return nextWeek.AddDate(0, 0, weekdayIndex(r.Weekdays[0]))
+	case Monthly:
+		dom := r.MonthDay
+		if dom == 0 {
+			dom = day.Day()
+		}
+		return monthDay(day.Year(), day.Month()+time.Month(interval), dom, day.Location())
+	case Yearly:
+		dom := r.MonthDay
+		if dom == 0 {
+			dom = day.Day()
+		}
+		return monthDay(day.Year()+interval, day.Month(), dom, day.Location())
+	}
+	return day
+}
+
+// anchored pins a monthly or yearly rule without a day of the month to the day
+// of the series' first due date. Otherwise a series starting on the 31st would
+// be clamped to the 28th in February and stay there for good.
+func (r Recurrence) anchored(due time.Time) *Recurrence {
+	if (r.Frequency == Monthly || r.Frequency == Yearly) && r.MonthDay == 0 {
+		r.MonthDay = due.Day()
+	}
+	r.Weekdays = append([]time.Weekday(nil), r.Weekdays...)
+	return &r
+}
+
+func (r Recurrence) onWeekday(t time.Time) bool {
+	for _, day := range r.Weekdays {
+		if t.Weekday() == day {
+			return true
+		}
+	}
+	return false
+}
+
+// StopRecurrence ends the series that the task with the given ID belongs to,
+// so completing its open instance no longer schedules another one.
+// Completed instances keep their rule as a record of the series.
+func (tm *TaskManager) StopRecurrence(id int) error {
+	return tm.mutateIdempotent("recur stop", func(tasks []Task) ([]Task, error) {
+		i := indexOf(tasks, id)
+		if i < 0 {
+			return nil, fmt.Errorf("task with ID %d not found", id)
+		}
+
+		series := tasks[i].seriesID()
+		stopped := false
+		for j := range tasks {
+			if tasks[j].Recur != nil && tasks[j].IsOpen() && tasks[j].seriesID() == series {
+				tasks[j].Recur = nil
+				tasks[j].UpdatedAt = time.Now()
+				stopped = true
+			}
+		}
+
+		if !stopped {
+			return nil, fmt.Errorf("task %d is not part of a recurring series", id)
+		}
+
+		return tasks, nil
+	})
+}
+
+// Recurring returns the open instance of every active recurring series.
+func (tm *TaskManager) Recurring() ([]Task, error) {
+	tasks, err := tm.loadLive()
+	if err != nil {
+		return nil, err
+	}
+
+	var recurring []Task
+	for _, t := range tasks {
+		if t.Recur != nil && t.IsOpen() {
+			recurring = append(recurring, t)
+		}
+	}
+	return recurring, nil
+}
+
+// seriesID identifies the recurring series a task belongs to: the ID of its first instance.
+func (t Task) seriesID() int {
+	if t.SeriesID != 0 {
+		return t.SeriesID
+	}
+	return t.ID
+}
+
+// nextInstance builds the task that follows t in its series, due on the first
+// occurrence after t's due date that is not already in the past.
+func nextInstance(t Task, id int, now time.Time) Task {
+	base := now
+	if t.DueAt != nil {
+		base = *t.DueAt
+	}
+	// Series created before rules were anchored are anchored here instead
+	recur := t.Recur.anchored(base)
+	due := recur.Next(base)
+	for due.Before(startOfDay(now)) {
+		due = recur.Next(due)
+	}
+
+	next := Task{
+		ID:          id,
+		Description: t.Description,
+		Status:      StatusPending,
+		Priority:    t.Priority,
+		DueAt:       &due,
+		Tags:        append([]string(nil), t.Tags...),
+		Project:     t.Project,
+		ParentID:    t.ParentID,
+		Recur:       recur,
+		SeriesID:    t.seriesID(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if t.Estimate != nil {
+		estimate := *t.Estimate
+		next.Estimate = &estimate
+	}
+	return next
+}
+
+// parseWeekday accepts a lowercase weekday name, in full or abbreviated to
+// its first three letters.
+func parseWeekday(s string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if s == name || s == name[:3] {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// weekdayIndex numbers weekdays from Monday (0) to Sunday (6).
+func weekdayIndex(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+// monthDay returns the given day of a month, clamped to the month's last day.
+func monthDay(year int, month time.Month, day int, loc *time.Location) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	last := first.AddDate(0, 1, -1).Day()
+	if day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, loc)
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
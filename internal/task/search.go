@@ -0,0 +1,349 @@
+package task
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SearchMode selects how a search query is matched against task descriptions.
+type SearchMode string
+
+const (
+	// SearchSubstring finds the query anywhere in the description.
+	SearchSubstring SearchMode = "substring"
+	// SearchWord finds the query only as whole words.
+	SearchWord SearchMode = "word"
+	// SearchRegex treats the query as a regular expression.
+	SearchRegex SearchMode = "regex"
+	// SearchFuzzy finds the letters of the query in order, possibly with
+	// others in between, and tolerates small typos in single words.
+	SearchFuzzy SearchMode = "fuzzy"
+)
+
+// SearchOptions controls how and where Search looks.
+type SearchOptions struct {
+	// IncludeArchive also searches archived tasks.
+	IncludeArchive bool
+	// Mode defaults to SearchSubstring. All modes ignore case.
+	Mode SearchMode
+	// Where keeps only tasks that also match a filter, such as a parsed query.
+	Where Matcher
+}
+
+// Span is a matched part of a description, as byte offsets [Start, End).
+type Span struct {
+	Start, End int
+}
+
+// SearchResult is a task found by a search, with how well it matched.
+type SearchResult struct {
+	Task Task
+	// Score ranks results within one search; higher is more relevant.
+	Score float64
+	// Spans are the matched parts of the description, in order.
+	Spans []Span
+}
+
+// Search returns the tasks whose description contains query, ignoring case,
+// most relevant first.
+func (tm *TaskManager) Search(query string) ([]Task, error) {
+	results, err := tm.SearchWithOptions(query, SearchOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]Task, len(results))
+	for i, r := range results {
+		found[i] = r.Task
+	}
+	return found, nil
+}
+
+// SearchWithOptions searches task descriptions for query and ranks the matches
+// by relevance. Live matches come first, followed by archived ones.
+func (tm *TaskManager) SearchWithOptions(query string, opts SearchOptions) ([]SearchResult, error) {
+	match, err := newSearcher(query, opts.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := tm.loadLive()
+	if err != nil {
+		return nil, err
+	}
+	found := rank(tasks, match, opts.Where)
+	if !opts.IncludeArchive {
+		return found, nil
+	}
+
+	archived, err := tm.Archived()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(found, rank(archived, match, opts.Where)...), nil
+}
+
+// searcher matches a description, returning its score and the matched spans.
+type searcher func(text string) (float64, []Span, bool)
+
+func newSearcher(query string, mode SearchMode) (searcher, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("please provide a search term")
+	}
+
+	switch mode {
+	case "", SearchSubstring:
+		return regexSearcher(regexp.MustCompile("(?i)"+regexp.QuoteMeta(query)), false), nil
+	case SearchWord:
+		return regexSearcher(regexp.MustCompile("(?i)"+regexp.QuoteMeta(query)), true), nil
+	case SearchRegex:
+		// Check the expression as written so errors quote the user's input
+		if _, err := regexp.Compile(query); err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return regexSearcher(regexp.MustCompile("(?i)"+query), false), nil
+	case SearchFuzzy:
+		return fuzzySearcher(query), nil
+	}
+	return nil, fmt.Errorf("invalid search mode %q (use %s, %s, %s or %s)", mode, SearchSubstring, SearchWord, SearchRegex, SearchFuzzy)
+}
+
+// rank returns the tasks that match, most relevant first. Ties keep ID order.
+func rank(tasks []Task, match searcher, where Matcher) []SearchResult {
+	var results []SearchResult
+	for _, t := range tasks {
+		if where != nil && !where.Match(t) {
+			continue
+		}
+		if score, spans, ok := match(t.Description); ok {
+			results = append(results, SearchResult{Task: t, Score: score, Spans: spans})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Task.ID < results[j].Task.ID
+	})
+	return results
+}
+
+// regexSearcher scores each match by whether it starts and ends on word
+// boundaries, plus the share of the description that matched, so whole-word
+// and near-complete matches rank first. With wholeWords, matches inside
+// words are ignored.
+func regexSearcher(re *regexp.Regexp, wholeWords bool) searcher {
+	return func(text string) (float64, []Span, bool) {
+		var score float64
+		var spans []Span
+		matched := 0
+
+		for _, m := range re.FindAllStringIndex(text, -1) {
+			start, end := m[0], m[1]
+			if start == end {
+				continue
+			}
+
+			startsWord, endsWord := wordStartAt(text, start), wordEndAt(text, end)
+			if wholeWords && !(startsWord && endsWord) {
+				continue
+			}
+
+			score++
+			if startsWord {
+				score += 0.5
+			}
+			if endsWord {
+				score += 0.5
+			}
+			spans = append(spans, Span{start, end})
+			matched += end - start
+		}
+
+		if len(spans) == 0 {
+			return 0, nil, false
+		}
+		return score + float64(matched)/float64(len(text)), spans, true
+	}
+}
+
+// fuzzySearcher matches the letters of query in order anywhere in the text.
+// Consecutive letters and letters at the start of a word score higher, gaps
+// between letters cost a little. If the letters are not all there, a word
+// within a typo or two of the query (one per four letters) still matches,
+// with a score below any in-order match.
+func fuzzySearcher(query string) searcher {
+	pattern := []rune(strings.ToLower(strings.Join(strings.Fields(query), "")))
+	maxTypos := len(pattern) / 4
+
+	return func(text string) (float64, []Span, bool) {
+		runes, offsets := lowerRunes(text)
+
+		if score, positions, ok := subsequence(runes, pattern); ok {
+			return score, runeSpans(positions, offsets), true
+		}
+
+		if maxTypos == 0 {
+			return 0, nil, false
+		}
+		best, bestSpan := maxTypos+1, Span{}
+		for _, w := range words(runes) {
+			start, end := w[0], w[1]
+			word := runes[start:end]
+			distance := editDistance(pattern, word)
+			// Also accept a typo in the first letters of a longer word
+			if len(word) > len(pattern) {
+				if d := editDistance(pattern, word[:len(pattern)]); d < distance {
+					distance, end = d, start+len(pattern)
+				}
+			}
+			if distance < best {
+				best, bestSpan = distance, Span{offsets[start], offsets[end]}
+			}
+		}
+		if best > maxTypos {
+			return 0, nil, false
+		}
+		return 0.5 * (1 - float64(best)/float64(len(pattern))), []Span{bestSpan}, true
+	}
+}
+
+// subsequence finds pattern in order within text, trying each place where its
+// first letter occurs and keeping the best scoring positions.
+func subsequence(text, pattern []rune) (float64, []int, bool) {
+	bestScore, found := 0.0, false
+	var best []int
+
+	for start := range text {
+		if text[start] != pattern[0] {
+			continue
+		}
+
+		positions := []int{start}
+		for i := start + 1; i < len(text) && len(positions) < len(pattern); i++ {
+			if text[i] == pattern[len(positions)] {
+				positions = append(positions, i)
+			}
+		}
+		if len(positions) < len(pattern) {
+			// Later starts cannot find the rest either
+			break
+		}
+
+		var score float64
+		for k, p := range positions {
+			score++
+			if p == 0 || !isWordRune(text[p-1]) {
+				score += 1.5
+			}
+			if k > 0 {
+				if gap := p - positions[k-1] - 1; gap == 0 {
+					score += 2
+				} else {
+					score -= 0.2 * float64(min(gap, 5))
+				}
+			}
+		}
+		// Offset in-order matches above the typo-tolerant ones
+		score = 0.5 + score/float64(len(pattern))
+
+		if !found || score > bestScore {
+			bestScore, best, found = score, positions, true
+		}
+	}
+
+	return bestScore, best, found
+}
+
+// editDistance is the optimal string alignment distance between a and b:
+// the insertions, deletions, substitutions and swaps of adjacent letters
+// needed to turn one into the other.
+func editDistance(a, b []rune) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+// lowerRunes lowercases text rune by rune, returning the byte offset of each
+// rune in text followed by len(text).
+func lowerRunes(text string) ([]rune, []int) {
+	runes := make([]rune, 0, len(text))
+	offsets := make([]int, 0, len(text)+1)
+	for i, r := range text {
+		runes = append(runes, unicode.ToLower(r))
+		offsets = append(offsets, i)
+	}
+	return runes, append(offsets, len(text))
+}
+
+// runeSpans converts sorted rune positions into byte spans, merging
+// neighbouring positions.
+func runeSpans(positions []int, offsets []int) []Span {
+	var spans []Span
+	for _, p := range positions {
+		if n := len(spans); n > 0 && spans[n-1].End == offsets[p] {
+			spans[n-1].End = offsets[p+1]
+			continue
+		}
+		spans = append(spans, Span{offsets[p], offsets[p+1]})
+	}
+	return spans
+}
+
+// words returns the [start, end) rune ranges of the words in text.
+func words(text []rune) [][2]int {
+	var result [][2]int
+	start := -1
+	for i, r := range text {
+		switch {
+		case isWordRune(r) && start < 0:
+			start = i
+		case !isWordRune(r) && start >= 0:
+			result = append(result, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		result = append(result, [2]int{start, len(text)})
+	}
+	return result
+}
+
+func wordStartAt(text string, i int) bool {
+	r, _ := utf8.DecodeLastRuneInString(text[:i])
+	return i == 0 || !isWordRune(r)
+}
+
+func wordEndAt(text string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(text[i:])
+	return i == len(text) || !isWordRune(r)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
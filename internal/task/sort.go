@@ -0,0 +1,39 @@
+package task
+
+import "sort"
+
+// SortByPriority orders tasks from the most to the least urgent priority,
+// breaking ties by ascending ID.
+func SortByPriority(tasks []Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Priority != tasks[j].Priority {
+			return tasks[i].Priority > tasks[j].Priority
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+}
+
+// SortByID orders tasks by ascending ID.
+func SortByID(tasks []Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].ID < tasks[j].ID
+	})
+}
+
+// SortByUrgency orders tasks by descending priority, then by earliest due date
+// (tasks without a due date last), then by ascending ID.
+func SortByUrgency(tasks []Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if (a.DueAt == nil) != (b.DueAt == nil) {
+			return a.DueAt != nil
+		}
+		if a.DueAt != nil && !a.DueAt.Equal(*b.DueAt) {
+			return a.DueAt.Before(*b.DueAt)
+		}
+		return a.ID < b.ID
+	})
+}
@@ -0,0 +1,145 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Groupings accepted by CycleTimeOptions.By.
+const (
+	GroupByProject = "project"
+	GroupByTag     = "tag"
+)
+
+// CycleTimeOptions selects the tasks and grouping for CycleTimes.
+type CycleTimeOptions struct {
+	// By groups tasks by GroupByProject or GroupByTag. A task with several tags
+	// counts towards each of them. Empty puts every task in a single group.
+	By string
+	// From and To limit the report to tasks completed in [From, To).
+	// A zero value leaves that end of the range open.
+	From, To time.Time
+}
+
+// Distribution summarizes a set of durations.
+type Distribution struct {
+	Count  int
+	Median time.Duration
+	P90    time.Duration
+}
+
+// CycleTimeStats reports how long the completed tasks of one group took.
+// Lead time runs from creation to completion; cycle time runs from when work
+// started to completion, so tasks that never went in progress have none.
+type CycleTimeStats struct {
+	// Group is the project or tag, empty for tasks without one, or "all"
+	// when the tasks are not grouped.
+	Group string
+	Lead  Distribution
+	Cycle Distribution
+}
+
+// CycleTimes computes lead and cycle time distributions of completed tasks,
+// including archived ones, sorted by group. Tasks without a recorded
+// completion time are left out; the storage backfills it for tasks completed
+// before it was recorded, so these are only tasks edited outside tm.
+func (tm *TaskManager) CycleTimes(opts CycleTimeOptions) ([]CycleTimeStats, error) {
+	if opts.By != "" && opts.By != GroupByProject && opts.By != GroupByTag {
+		return nil, fmt.Errorf("invalid grouping %q (use %s or %s)", opts.By, GroupByProject, GroupByTag)
+	}
+
+	tasks, err := tm.loadLive()
+	if err != nil {
+		return nil, err
+	}
+	if tm.archive != nil {
+		archived, err := tm.Archived()
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, archived...)
+	}
+
+	lead := map[string][]time.Duration{}
+	cycle := map[string][]time.Duration{}
+	for _, t := range tasks {
+		if !t.IsDone() || t.CompletedAt == nil {
+			continue
+		}
+		completed := *t.CompletedAt
+		if (!opts.From.IsZero() && completed.Before(opts.From)) || (!opts.To.IsZero() && !completed.Before(opts.To)) {
+			continue
+		}
+
+		for _, group := range groupsOf(t, opts.By) {
+			lead[group] = append(lead[group], nonNegative(completed.Sub(t.CreatedAt)))
+			if t.StartedAt != nil {
+				cycle[group] = append(cycle[group], nonNegative(completed.Sub(*t.StartedAt)))
+			}
+		}
+	}
+
+	stats := make([]CycleTimeStats, 0, len(lead))
+	for group, durations := range lead {
+		stats = append(stats, CycleTimeStats{
+			Group: group,
+			Lead:  distribution(durations),
+			Cycle: distribution(cycle[group]),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Group < stats[j].Group
+	})
+
+	return stats, nil
+}
+
+// groupsOf returns the groups a task is counted in.
+func groupsOf(t Task, by string) []string {
+	switch by {
+	case GroupByProject:
+		return []string{t.Project}
+	case GroupByTag:
+		if len(t.Tags) == 0 {
+			return []string{""}
+		}
+		return t.Tags
+	}
+	return []string{"all"}
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	return max(d, 0)
+}
+
+// distribution computes the median and 90th percentile of durations.
+func distribution(durations []time.Duration) Distribution {
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	return Distribution{
+		Count:  len(sorted),
+		Median: percentile(sorted, 0.5),
+		P90:    percentile(sorted, 0.9),
+	}
+}
+
+// percentile interpolates linearly between the closest ranks of sorted
+// durations, so the 50th percentile of an even count is the mean of the two
+// middle values.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := p * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower+1 >= len(sorted) {
+		return sorted[lower]
+	}
+	fraction := rank - float64(lower)
+	return sorted[lower] + time.Duration(fraction*float64(sorted[lower+1]-sorted[lower]))
+}
@@ -0,0 +1,103 @@
+package task
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetParent makes the task with the given ID a subtask of parentID.
+// A parentID of 0 turns it back into a top-level task. Cycles are rejected.
+func (tm *TaskManager) SetParent(id, parentID int) error {
+	return tm.mutateIdempotent("parent", func(tasks []Task) ([]Task, error) {
+		i := indexOf(tasks, id)
+		if i < 0 {
+			return nil, fmt.Errorf("task with ID %d not found", id)
+		}
+
+		if parentID != 0 {
+			if indexOf(tasks, parentID) < 0 {
+				return nil, fmt.Errorf("parent task with ID %d not found", parentID)
+			}
+			if createsCycle(tasks, id, parentID) {
+				return nil, fmt.Errorf("cannot make task %d a subtask of %d: that would create a cycle", id, parentID)
+			}
+		}
+
+		tasks[i].ParentID = parentID
+		tasks[i].UpdatedAt = time.Now()
+
+		return tasks, nil
+	})
+}
+
+// ChildCounts returns, for every task with subtasks, how many direct subtasks it has
+// and how many of those are done.
+func ChildCounts(tasks []Task) (total, done map[int]int) {
+	total, done = map[int]int{}, map[int]int{}
+	for _, t := range tasks {
+		if t.ParentID == 0 {
+			continue
+		}
+		total[t.ParentID]++
+		if t.IsDone() {
+			done[t.ParentID]++
+		}
+	}
+	return total, done
+}
+
+// createsCycle reports whether making id a child of parentID would make id its own ancestor.
+func createsCycle(tasks []Task, id, parentID int) bool {
+	seen := map[int]bool{}
+	for current := parentID; current != 0 && !seen[current]; {
+		if current == id {
+			return true
+		}
+		seen[current] = true
+
+		i := indexOf(tasks, current)
+		if i < 0 {
+			break
+		}
+		current = tasks[i].ParentID
+	}
+	return false
+}
+
+// descendantIDs returns the IDs of all subtasks below id, level by level,
+// leaving out subtasks in the trash.
+func descendantIDs(tasks []Task, id int) []int {
+	var ids []int
+	seen := map[int]bool{id: true}
+	for queue := []int{id}; len(queue) > 0; queue = queue[1:] {
+		for _, t := range tasks {
+			if t.ParentID == queue[0] && !seen[t.ID] && !t.IsDeleted() {
+				seen[t.ID] = true
+				ids = append(ids, t.ID)
+				queue = append(queue, t.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// allDescendants reports whether every subtask below id, leaving out subtasks
+// in the trash, satisfies ok.
+func allDescendants(tasks []Task, id int, ok func(Task) bool) bool {
+	for _, d := range descendantIDs(tasks, id) {
+		if !ok(tasks[indexOf(tasks, d)]) {
+			return false
+		}
+	}
+	return true
+}
+
+// allChildrenClosed reports whether every direct subtask of id is done or cancelled.
+func allChildrenClosed(tasks []Task, id int) bool {
+	for _, t := range tasks {
+		if t.ParentID == id && t.IsOpen() && !t.IsDeleted() {
+			return false
+		}
+	}
+	return true
+}
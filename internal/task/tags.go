@@ -0,0 +1,149 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TagCount summarizes how many open and completed tasks carry a tag.
+// Cancelled tasks are counted in neither.
+type TagCount struct {
+	Tag  string
+	Open int
+	Done int
+}
+
+// NormalizeTag validates a tag name, stripping a leading "+" and lowercasing it.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "+"))
+	if tag == "" {
+		return "", fmt.Errorf("tag cannot be empty")
+	}
+	if strings.ContainsAny(tag, " \t\n+") || strings.HasPrefix(tag, "-") {
+		return "", fmt.Errorf("invalid tag %q", tag)
+	}
+	return tag, nil
+}
+
+// ExtractTags removes +tag tokens from a description and returns the
+// remaining text together with the normalized, de-duplicated tags.
+func ExtractTags(description string) (string, []string) {
+	var words, tags []string
+	for _, word := range strings.Fields(description) {
+		if len(word) > 1 && strings.HasPrefix(word, "+") {
+			if tag, err := NormalizeTag(word); err == nil {
+				tags = addTag(tags, tag)
+				continue
+			}
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " "), tags
+}
+
+// HasTag reports whether the task carries the given tag.
+func (t Task) HasTag(tag string) bool {
+	for _, existing := range t.Tags {
+		if existing == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Tag adds and removes tags on the task with the given ID.
+func (tm *TaskManager) Tag(id int, add, remove []string) error {
+	add, err := normalizeTags(add)
+	if err != nil {
+		return err
+	}
+	remove, err = normalizeTags(remove)
+	if err != nil {
+		return err
+	}
+
+	return tm.mutateIdempotent("tag", func(tasks []Task) ([]Task, error) {
+		i := indexOf(tasks, id)
+		if i < 0 {
+			return nil, fmt.Errorf("task with ID %d not found", id)
+		}
+
+		for _, tag := range add {
+			tasks[i].Tags = addTag(tasks[i].Tags, tag)
+		}
+		for _, tag := range remove {
+			tasks[i].Tags = removeTag(tasks[i].Tags, tag)
+		}
+		tasks[i].UpdatedAt = time.Now()
+
+		return tasks, nil
+	})
+}
+
+// TagCounts returns every tag in use with its open and done task counts, sorted by tag.
+func (tm *TaskManager) TagCounts() ([]TagCount, error) {
+	tasks, err := tm.loadLive()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]*TagCount{}
+	for _, t := range tasks {
+		for _, tag := range t.Tags {
+			c, ok := counts[tag]
+			if !ok {
+				c = &TagCount{Tag: tag}
+				counts[tag] = c
+			}
+			switch {
+			case t.IsDone():
+				c.Done++
+			case t.IsOpen():
+				c.Open++
+			}
+		}
+	}
+
+	result := make([]TagCount, 0, len(counts))
+	for _, c := range counts {
+		result = append(result, *c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Tag < result[j].Tag
+	})
+
+	return result, nil
+}
+
+func normalizeTags(tags []string) ([]string, error) {
+	var result []string
+	for _, tag := range tags {
+		normalized, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		result = addTag(result, normalized)
+	}
+	return result, nil
+}
+
+func addTag(tags []string, tag string) []string {
+	for _, existing := range tags {
+		if existing == tag {
+			return tags
+		}
+	}
+	return append(tags, tag)
+}
+
+func removeTag(tags []string, tag string) []string {
+	var result []string
+	for _, existing := range tags {
+		if existing != tag {
+			result = append(result, existing)
+		}
+	}
+	return result
+}
@@ -1,18 +1,178 @@
 package task
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 type Task struct {
-	ID          int       `json:"id"`
-	Description string    `json:"description"`
-	Done        bool      `json:"done"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+	ID          int         `json:"id"`
+	Description string      `json:"description"`
+	Status      TaskStatus  `json:"status"`
+	Priority    Priority    `json:"priority,omitempty"`
+	DueAt       *time.Time  `json:"due_at,omitempty"`
+	Tags        []string    `json:"tags,omitempty"`
+	Project     string      `json:"project,omitempty"`
+	ParentID    int         `json:"parent_id,omitempty"`
+	DependsOn   []int       `json:"depends_on,omitempty"`
+	Recur       *Recurrence `json:"recur,omitempty"`
+	SeriesID    int         `json:"series_id,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at,omitempty"`
+	// StartedAt is when the task was first moved to in-progress.
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	// CompletedAt is when the task was last marked done; it is cleared on reopening.
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// Estimate is the expected effort, if one was given.
+	Estimate *Estimate `json:"estimate,omitempty"`
+	// Tracked holds the time tracked on the task, oldest first.
+	Tracked []Interval `json:"tracked,omitempty"`
+	// DeletedAt is set while the task is in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// IsDone reports whether the task has been completed.
+func (t Task) IsDone() bool {
+	return t.Status == StatusDone
+}
+
+// IsOpen reports whether the task still needs work, i.e. it is neither done nor cancelled.
+func (t Task) IsOpen() bool {
+	return t.Status != StatusDone && t.Status != StatusCancelled
+}
+
+// IsDeleted reports whether the task has been moved to the trash.
+func (t Task) IsDeleted() bool {
+	return t.DeletedAt != nil
+}
+
+// setStatus moves the task to status at now, noting when work on it first
+// started and when it was completed. Closing a task stops its timer.
+func (t *Task) setStatus(status TaskStatus, now time.Time) {
+	t.Status = status
+	t.UpdatedAt = now
+	if !t.IsOpen() {
+		t.stopTimer(now)
+	}
+
+	if status == StatusInProgress && t.StartedAt == nil {
+		started := now
+		t.StartedAt = &started
+	}
+
+	t.CompletedAt = nil
+	if status == StatusDone {
+		completed := now
+		t.CompletedAt = &completed
+	}
+}
+
+// IsOverdue reports whether the task is still open and was due before the day of now.
+func (t Task) IsOverdue(now time.Time) bool {
+	if !t.IsOpen() || t.DueAt == nil {
+		return false
+	}
+	return t.DueAt.Before(startOfDay(now))
 }
 
 type TaskStatus string
 
 const (
-	StatusPending TaskStatus = "pending"
-	StatusDone    TaskStatus = "done"
+	StatusPending    TaskStatus = "pending"
+	StatusInProgress TaskStatus = "in-progress"
+	StatusBlocked    TaskStatus = "blocked"
+	StatusWaiting    TaskStatus = "waiting"
+	StatusDone       TaskStatus = "done"
+	StatusCancelled  TaskStatus = "cancelled"
+)
+
+// Statuses lists every status in lifecycle order.
+var Statuses = []TaskStatus{
+	StatusPending, StatusInProgress, StatusBlocked, StatusWaiting, StatusDone, StatusCancelled,
+}
+
+// transitions lists the statuses each status may move to.
+// Closed tasks (done, cancelled) can only be reopened.
+var transitions = map[TaskStatus][]TaskStatus{
+	StatusPending:    {StatusInProgress, StatusBlocked, StatusWaiting, StatusDone, StatusCancelled},
+	StatusInProgress: {StatusPending, StatusBlocked, StatusWaiting, StatusDone, StatusCancelled},
+	StatusBlocked:    {StatusPending, StatusInProgress, StatusWaiting, StatusDone, StatusCancelled},
+	StatusWaiting:    {StatusPending, StatusInProgress, StatusBlocked, StatusDone, StatusCancelled},
+	StatusDone:       {StatusPending},
+	StatusCancelled:  {StatusPending},
+}
+
+// ParseStatus converts a status name such as "in-progress" into a TaskStatus.
+func ParseStatus(s string) (TaskStatus, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, status := range Statuses {
+		if s == string(status) {
+			return status, nil
+		}
+	}
+	return "", fmt.Errorf("invalid status %q", s)
+}
+
+// CanTransition reports whether a task may move from one status to another.
+func CanTransition(from, to TaskStatus) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Priority ranks how urgent a task is. The zero value is PriorityNone,
+// so tasks stored before priorities existed load as having no priority.
+type Priority int
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+	PriorityUrgent
 )
+
+var priorityNames = []string{"none", "low", "medium", "high", "urgent"}
+
+// ParsePriority converts a level name such as "high" into a Priority.
+// An empty string is treated as PriorityNone.
+func ParsePriority(s string) (Priority, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return PriorityNone, nil
+	}
+	for i, name := range priorityNames {
+		if s == name {
+			return Priority(i), nil
+		}
+	}
+	return PriorityNone, fmt.Errorf("invalid priority %q (use one of: %s)", s, strings.Join(priorityNames, ", "))
+}
+
+func (p Priority) String() string {
+	if p < 0 || int(p) >= len(priorityNames) {
+		return fmt.Sprintf("Priority(%d)", int(p))
+	}
+	return priorityNames[p]
+}
+
+// MarshalText stores priorities by name so the JSON file stays readable.
+func (p Priority) MarshalText() ([]byte, error) {
+	if p < 0 || int(p) >= len(priorityNames) {
+		return nil, fmt.Errorf("invalid priority %d", int(p))
+	}
+	return []byte(p.String()), nil
+}
+
+func (p *Priority) UnmarshalText(text []byte) error {
+	parsed, err := ParsePriority(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
@@ -1,19 +1,54 @@
 package task
 
 import (
+	"errors"
 	"fmt"
-	"strings"
 	"time"
 )
 
-// Repository interface for storage abstraction
+// Repository interface for storage abstraction.
+// Load returns the tasks together with a revision identifying what was read.
+// Save only writes if the stored tasks are still at the given base revision,
+// returning ErrConflict otherwise, and returns the new revision.
 type Repository interface {
-	Load() ([]Task, error)
-	Save(tasks []Task) error
+	Load() ([]Task, Revision, error)
+	Save(tasks []Task, base Revision) (Revision, error)
+}
+
+// Revision is an opaque token identifying one version of the stored tasks.
+type Revision string
+
+// ErrConflict is returned when the stored tasks were modified by someone else
+// between loading and saving them.
+var ErrConflict = errors.New("tasks were modified concurrently")
+
+// maxConflictRetries is how many times an idempotent operation is retried
+// after a conflicting concurrent modification.
+const maxConflictRetries = 3
+
+// Locker is implemented by repositories that can be shared between processes.
+// Lock blocks other processes from modifying the tasks until the returned
+// function is called, so a load-modify-save cycle cannot lose their writes.
+type Locker interface {
+	Lock() (unlock func(), err error)
+}
+
+// IDKeeper is implemented by repositories that remember the highest task ID
+// they have ever saved. Purged tasks leave no trace in the task list, so
+// without it their IDs would be handed out again.
+type IDKeeper interface {
+	// LastID returns the highest ID ever saved, or 0 for an empty repository.
+	LastID() (int, error)
+	// ReserveID marks every ID up to id as used. It never lowers LastID.
+	ReserveID(id int) error
 }
 
 type TaskManager struct {
-	repo Repository
+	repo      Repository
+	journal   Journal
+	archive   Repository
+	changeLog ChangeLog
+	actor     string
 }
 
 func NewTaskManager(repo Repository) (*TaskManager, error) {
@@ -21,7 +56,7 @@ func NewTaskManager(repo Repository) (*TaskManager, error) {
 	// It loads the existing tasks from the repository, and if the file doesn't exist,
 	// it initializes the TaskManager with an empty task list.
 	// If there is an issue loading the tasks, an error will be returned.
-	tasks, err := repo.Load()
+	tasks, _, err := repo.Load()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load tasks: %w", err)
 	}
@@ -34,106 +69,355 @@ func NewTaskManager(repo Repository) (*TaskManager, error) {
 	return &TaskManager{repo: repo}, nil
 }
 
+// Repository returns the repository the tasks are stored in.
+func (tm *TaskManager) Repository() Repository {
+	return tm.repo
+}
+
+// CopyTo copies every task, including those in the trash, into dst and returns
+// how many were copied. It refuses to overwrite a repository that already holds tasks.
+func (tm *TaskManager) CopyTo(dst Repository) (int, error) {
+	unlock, err := tm.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	if l, ok := dst.(Locker); ok {
+		unlockDst, err := l.Lock()
+		if err != nil {
+			return 0, err
+		}
+		defer unlockDst()
+	}
+
+	tasks, _, err := tm.repo.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	existing, rev, err := dst.Load()
+	if err != nil {
+		return 0, err
+	}
+	if len(existing) > 0 {
+		return 0, fmt.Errorf("destination already holds %d task(s)", len(existing))
+	}
+
+	if _, err := dst.Save(tasks, rev); err != nil {
+		return 0, err
+	}
+
+	// Carry over the IDs of purged tasks so the copy does not reuse them
+	src, srcOK := tm.repo.(IDKeeper)
+	keeper, dstOK := dst.(IDKeeper)
+	if srcOK && dstOK {
+		last, err := src.LastID()
+		if err != nil {
+			return 0, err
+		}
+		if err := keeper.ReserveID(last); err != nil {
+			return 0, err
+		}
+	}
+	return len(tasks), nil
+}
+
+// AddOptions holds the optional attributes of a new task.
+type AddOptions struct {
+	Priority Priority
+	DueAt    *time.Time
+	Tags     []string
+	Project  string
+	ParentID int
+	// Recur makes the task repeat; without DueAt the first occurrence becomes the due date.
+	Recur    *Recurrence
+	Estimate *Estimate
+}
+
+// DoneOptions controls how MarkDoneWithOptions completes a task.
+type DoneOptions struct {
+	// Force completes the task even if some of its prerequisites are still open.
+	Force bool
+	// CompleteParents also marks a parent done once all of its subtasks are closed,
+	// repeating up the hierarchy.
+	CompleteParents bool
+}
+
+// DoneResult reports the side effects of completing a task.
+type DoneResult struct {
+	// AutoCompleted holds the IDs of parents completed because all their subtasks were closed.
+	AutoCompleted []int
+	// NextID is the ID of the next instance spawned for a recurring task, or 0.
+	NextID int
+}
+
+// DeleteOptions controls how DeleteWithOptions treats subtasks.
+type DeleteOptions struct {
+	// Cascade deletes the task together with all of its subtasks.
+	// Without it, tasks that still have subtasks cannot be deleted.
+	Cascade bool
+}
+
 // -------------------
 func (tm *TaskManager) Add(description string) (int, error) {
-	description = strings.TrimSpace(description)
+	return tm.AddWithOptions(description, AddOptions{})
+}
+
+// AddWithOptions creates a task with the given description and optional attributes
+// and returns its ID. Any +tag tokens in the description are moved into the task's tags.
+func (tm *TaskManager) AddWithOptions(description string, opts AddOptions) (int, error) {
+	description, tags := ExtractTags(description)
 	if description == "" {
 		return 0, fmt.Errorf("description cannot be empty")
 	}
 
-	tasks, err := tm.repo.Load()
+	extra, err := normalizeTags(opts.Tags)
 	if err != nil {
 		return 0, err
 	}
+	for _, tag := range extra {
+		tags = addTag(tags, tag)
+	}
 
-	// Generate new ID
-	maxID := 0
-	for _, t := range tasks {
-		if t.ID > maxID {
-			maxID = t.ID
-		}
+	project, err := NormalizeProject(opts.Project)
+	if err != nil {
+		return 0, err
 	}
 
-	newTask := Task{
-		ID:          maxID + 1,
-		Description: description,
-		Done:        false,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+	dueAt := opts.DueAt
+	recur := opts.Recur
+	if recur != nil {
+		if dueAt == nil {
+			first := recur.First(time.Now())
+			dueAt = &first
+		}
+		recur = recur.anchored(*dueAt)
 	}
 
-	tasks = append(tasks, newTask)
+	var id int
+	err = tm.mutate("add", func(tasks []Task) ([]Task, error) {
+		if opts.ParentID != 0 && indexOf(tasks, opts.ParentID) < 0 {
+			return nil, fmt.Errorf("parent task with ID %d not found", opts.ParentID)
+		}
+
+		newID, err := tm.newID(tasks)
+		if err != nil {
+			return nil, err
+		}
+
+		newTask := Task{
+			ID:          newID,
+			Description: description,
+			Status:      StatusPending,
+			Priority:    opts.Priority,
+			DueAt:       dueAt,
+			Tags:        tags,
+			Project:     project,
+			ParentID:    opts.ParentID,
+			Recur:       recur,
+			Estimate:    opts.Estimate,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		}
+		id = newTask.ID
 
-	if err := tm.repo.Save(tasks); err != nil {
+		return append(tasks, newTask), nil
+	})
+	if err != nil {
 		return 0, err
 	}
 
-	return newTask.ID, nil
+	return id, nil
 }
 
+// List returns every task that is not in the trash.
 func (tm *TaskManager) List() ([]Task, error) {
-	return tm.repo.Load()
+	return tm.loadLive()
 }
 
 func (tm *TaskManager) MarkDone(id int) error {
-	tasks, err := tm.repo.Load()
+	_, err := tm.MarkDoneWithOptions(id, DoneOptions{})
+	return err
+}
+
+// MarkDoneWithOptions completes the task with the given ID and reports any parents
+// that were completed along with it.
+func (tm *TaskManager) MarkDoneWithOptions(id int, opts DoneOptions) (DoneResult, error) {
+	var result DoneResult
+
+	err := tm.mutate("done", func(tasks []Task) ([]Task, error) {
+		i := indexOf(tasks, id)
+		if i < 0 {
+			return nil, fmt.Errorf("task with ID %d not found", id)
+		}
+
+		if err := checkTransition(tasks[i], StatusDone); err != nil {
+			return nil, err
+		}
+
+		if blockers := openPrerequisites(tasks, tasks[i]); len(blockers) > 0 && !opts.Force {
+			return nil, fmt.Errorf("task %d depends on open task(s) %s; complete them first or force", id, FormatIDs(blockers))
+		}
+
+		now := time.Now()
+		tasks[i].setStatus(StatusDone, now)
+
+		if opts.CompleteParents {
+			for p := indexOf(tasks, tasks[i].ParentID); p >= 0; p = indexOf(tasks, tasks[p].ParentID) {
+				if !tasks[p].IsOpen() || !allChildrenClosed(tasks, tasks[p].ID) || len(openPrerequisites(tasks, tasks[p])) > 0 {
+					break
+				}
+				tasks[p].setStatus(StatusDone, now)
+				result.AutoCompleted = append(result.AutoCompleted, tasks[p].ID)
+			}
+		}
+
+		// Schedule the next instance of a recurring task
+		if tasks[i].Recur != nil {
+			nextTaskID, err := tm.newID(tasks)
+			if err != nil {
+				return nil, err
+			}
+			next := nextInstance(tasks[i], nextTaskID, now)
+			tasks = append(tasks, next)
+			result.NextID = next.ID
+		}
+
+		return tasks, nil
+	})
 	if err != nil {
-		return err
+		return DoneResult{}, err
 	}
 
-	found := false
-	for i := range tasks {
-		if tasks[i].ID == id {
-			tasks[i].Done = true
-			tasks[i].UpdatedAt = time.Now()
-			found = true
-			break
+	return result, nil
+}
+
+// SetStatus moves the task with the given ID to a new status.
+// It returns an error if the lifecycle does not allow the transition.
+func (tm *TaskManager) SetStatus(id int, status TaskStatus) error {
+	return tm.mutateIdempotent("status "+string(status), func(tasks []Task) ([]Task, error) {
+		i := indexOf(tasks, id)
+		if i < 0 {
+			return nil, fmt.Errorf("task with ID %d not found", id)
 		}
-	}
 
-	if !found {
-		return fmt.Errorf("task with ID %d not found", id)
-	}
+		if err := checkTransition(tasks[i], status); err != nil {
+			return nil, err
+		}
+
+		tasks[i].setStatus(status, time.Now())
+
+		return tasks, nil
+	})
+}
+
+// SetPriority changes the priority of the task with the given ID.
+func (tm *TaskManager) SetPriority(id int, priority Priority) error {
+	return tm.mutateIdempotent("priority "+priority.String(), func(tasks []Task) ([]Task, error) {
+		i := indexOf(tasks, id)
+		if i < 0 {
+			return nil, fmt.Errorf("task with ID %d not found", id)
+		}
+
+		tasks[i].Priority = priority
+		tasks[i].UpdatedAt = time.Now()
 
-	return tm.repo.Save(tasks)
+		return tasks, nil
+	})
 }
 
 func (tm *TaskManager) Delete(id int) error {
-	tasks, err := tm.repo.Load()
-	if err != nil {
-		return err
-	}
+	_, err := tm.DeleteWithOptions(id, DeleteOptions{})
+	return err
+}
+
+// DeleteWithOptions moves the task with the given ID to the trash and returns the
+// IDs of every task that was trashed, including subtasks when cascading.
+// Trashed tasks keep their data and can be restored until they are purged.
+func (tm *TaskManager) DeleteWithOptions(id int, opts DeleteOptions) ([]int, error) {
+	var removed []int
 
-	found := false
-	for i, t := range tasks {
-		if t.ID == id {
-			tasks = append(tasks[:i], tasks[i+1:]...)
-			found = true
-			break
+	err := tm.mutateIdempotent("delete", func(tasks []Task) ([]Task, error) {
+		if indexOf(tasks, id) < 0 {
+			return nil, fmt.Errorf("task with ID %d not found", id)
 		}
-	}
 
-	if !found {
-		return fmt.Errorf("task with ID %d not found", id)
-	}
+		removed = []int{id}
+		descendants := descendantIDs(tasks, id)
+		if len(descendants) > 0 {
+			if !opts.Cascade {
+				return nil, fmt.Errorf("task %d has %d subtask(s); delete them first or cascade", id, len(descendants))
+			}
+			removed = append(removed, descendants...)
+		}
 
-	return tm.repo.Save(tasks)
-}
+		// All tasks trashed together share one timestamp so they are restored together
+		now := time.Now()
+		for _, r := range removed {
+			i := indexOf(tasks, r)
+			tasks[i].DeletedAt = &now
+			// Time in the trash is not time worked
+			tasks[i].stopTimer(now)
+		}
 
-func (tm *TaskManager) Search(query string) ([]Task, error) {
-	tasks, err := tm.repo.Load()
+		return tasks, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	query = strings.ToLower(query)
-	var found []Task
+	return removed, nil
+}
 
+// lock takes the repository's lock if it has one.
+func (tm *TaskManager) lock() (func(), error) {
+	if l, ok := tm.repo.(Locker); ok {
+		return l.Lock()
+	}
+	return func() {}, nil
+}
+
+// nextID returns one more than the highest ID in tasks, counting tasks in the
+// trash so a restored task never clashes with a new one. Use TaskManager.newID,
+// which also accounts for archived and purged tasks.
+func nextID(tasks []Task) int {
+	maxID := 0
 	for _, t := range tasks {
-		if strings.Contains(strings.ToLower(t.Description), query) {
-			found = append(found, t)
+		if t.ID > maxID {
+			maxID = t.ID
+		}
+	}
+	return maxID + 1
+}
+
+// indexOf returns the position of the task with the given ID, or -1 if there is
+// none. Tasks in the trash are not found.
+func indexOf(tasks []Task, id int) int {
+	i := indexOfAny(tasks, id)
+	if i >= 0 && tasks[i].IsDeleted() {
+		return -1
+	}
+	return i
+}
+
+// indexOfAny is like indexOf but also finds tasks in the trash.
+func indexOfAny(tasks []Task, id int) int {
+	for i := range tasks {
+		if tasks[i].ID == id {
+			return i
 		}
 	}
+	return -1
+}
 
-	return found, nil
+// checkTransition returns a descriptive error if t cannot move to status.
+func checkTransition(t Task, status TaskStatus) error {
+	if t.Status == status {
+		return fmt.Errorf("task %d is already %s", t.ID, status)
+	}
+	if !CanTransition(t.Status, status) {
+		return fmt.Errorf("task %d cannot go from %s to %s", t.ID, t.Status, status)
+	}
+	return nil
 }
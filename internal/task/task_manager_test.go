@@ -1,7 +1,11 @@
 package task
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -14,37 +18,133 @@ type MockRepository struct {
 	loadCalled int
 	saveCalled int
 	lastSaved  []Task
+	revision   int
+	lastID     int
+	// conflicts is the number of upcoming saves that find the tasks
+	// modified by someone else since they were loaded
+	conflicts int
 }
 
 // Load implements Repository interface
-func (m *MockRepository) Load() ([]Task, error) {
+func (m *MockRepository) Load() ([]Task, Revision, error) {
 	m.loadCalled++
 	if m.loadError != nil {
-		return nil, m.loadError
+		return nil, "", m.loadError
 	}
-	return m.tasks, nil
+	return cloneTasks(m.tasks), Revision(strconv.Itoa(m.revision)), nil
 }
 
 // Save implements Repository interface
-func (m *MockRepository) Save(tasks []Task) error {
+func (m *MockRepository) Save(tasks []Task, base Revision) (Revision, error) {
 	m.saveCalled++
+	if m.conflicts > 0 {
+		m.conflicts--
+		m.revision++
+	}
+	if base != Revision(strconv.Itoa(m.revision)) {
+		return "", ErrConflict
+	}
+
 	m.lastSaved = tasks
 
 	if m.saveError != nil {
-		return m.saveError
+		return "", m.saveError
 	}
 
 	m.tasks = tasks
+	m.revision++
+	for _, t := range tasks {
+		m.lastID = max(m.lastID, t.ID)
+	}
+	return Revision(strconv.Itoa(m.revision)), nil
+}
+
+// LastID implements IDKeeper interface
+func (m *MockRepository) LastID() (int, error) {
+	return m.lastID, nil
+}
+
+// ReserveID implements IDKeeper interface
+func (m *MockRepository) ReserveID(id int) error {
+	m.lastID = max(m.lastID, id)
+	return nil
+}
+
+// MockJournal keeps journal entries in memory for testing
+type MockJournal struct {
+	entries []JournalEntry
+}
+
+// LoadJournal implements Journal interface
+func (m *MockJournal) LoadJournal() ([]JournalEntry, error) {
+	return m.entries, nil
+}
+
+// SaveJournal implements Journal interface
+func (m *MockJournal) SaveJournal(entries []JournalEntry) error {
+	m.entries = entries
+	return nil
+}
+
+// MockChangeLog keeps recorded changes in memory for testing
+type MockChangeLog struct {
+	changes []Change
+}
+
+// AppendChanges implements ChangeLog interface
+func (m *MockChangeLog) AppendChanges(changes []Change) error {
+	m.changes = append(m.changes, changes...)
 	return nil
 }
 
+// LoadChanges implements ChangeLog interface
+func (m *MockChangeLog) LoadChanges(id int) ([]Change, error) {
+	var changes []Change
+	for _, c := range m.changes {
+		if c.TaskID == id {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+// LockingRepository is a MockRepository that also implements Locker
+type LockingRepository struct {
+	MockRepository
+	lockError error
+	locked    bool
+	lockCalls int
+}
+
+// Lock implements Locker interface
+func (m *LockingRepository) Lock() (func(), error) {
+	if m.lockError != nil {
+		return nil, m.lockError
+	}
+	m.lockCalls++
+	m.locked = true
+	return func() { m.locked = false }, nil
+}
+
+// Save implements Repository interface and checks the lock is held
+func (m *LockingRepository) Save(tasks []Task, base Revision) (Revision, error) {
+	if !m.locked {
+		return "", errors.New("saved without holding the lock")
+	}
+	return m.MockRepository.Save(tasks, base)
+}
+
 // TestHelper: Creates a task with specific fields
 func createTestTask(id int, description string, done bool) Task {
 	now := time.Now()
+	status := StatusPending
+	if done {
+		status = StatusDone
+	}
 	return Task{
 		ID:          id,
 		Description: description,
-		Done:        done,
+		Status:      status,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
@@ -129,7 +229,7 @@ func TestAdd(t *testing.T) {
 		}
 
 		savedTask := mockRepo.lastSaved[0]
-		if savedTask.ID != 1 || savedTask.Description != "Buy groceries" || savedTask.Done {
+		if savedTask.ID != 1 || savedTask.Description != "Buy groceries" || savedTask.Status != StatusPending {
 			t.Errorf("Task not saved correctly: %+v", savedTask)
 		}
 	})
@@ -257,11 +357,11 @@ func TestMarkDone(t *testing.T) {
 			t.Errorf("Expected Save to be called once, got %d", mockRepo.saveCalled)
 		}
 
-		if !mockRepo.lastSaved[0].Done {
+		if !mockRepo.lastSaved[0].IsDone() {
 			t.Error("Task 1 should be marked as done")
 		}
 
-		if mockRepo.lastSaved[1].Done {
+		if mockRepo.lastSaved[1].IsDone() {
 			t.Error("Task 2 should not be marked as done")
 		}
 	})
@@ -304,9 +404,92 @@ func TestMarkDone(t *testing.T) {
 	})
 }
 
+// TestSetStatus tests the status lifecycle
+func TestSetStatus(t *testing.T) {
+	t.Run("Follows allowed transitions", func(t *testing.T) {
+		mockRepo := &MockRepository{
+			tasks: []Task{createTestTask(1, "Task 1", false)},
+		}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		steps := []TaskStatus{StatusInProgress, StatusBlocked, StatusInProgress, StatusDone, StatusPending, StatusCancelled}
+		for _, status := range steps {
+			if err := tm.SetStatus(1, status); err != nil {
+				t.Fatalf("Expected transition to %s to succeed, got %v", status, err)
+			}
+			if mockRepo.lastSaved[0].Status != status {
+				t.Fatalf("Expected status %s, got %s", status, mockRepo.lastSaved[0].Status)
+			}
+		}
+	})
+
+	t.Run("Rejects invalid transitions", func(t *testing.T) {
+		mockRepo := &MockRepository{
+			tasks: []Task{createTestTask(1, "Task 1", true)},
+		}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		if err := tm.SetStatus(1, StatusInProgress); err == nil {
+			t.Error("Expected error moving done task to in-progress")
+		}
+		if err := tm.MarkDone(1); err == nil {
+			t.Error("Expected error marking done task as done again")
+		}
+		if mockRepo.saveCalled > 0 {
+			t.Error("Save should not be called for rejected transitions")
+		}
+	})
+
+	t.Run("Records start and completion times", func(t *testing.T) {
+		mockRepo := &MockRepository{
+			tasks: []Task{createTestTask(1, "Task 1", false)},
+		}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		tm.SetStatus(1, StatusInProgress)
+		started := mockRepo.lastSaved[0].StartedAt
+		if started == nil {
+			t.Fatal("Expected a start time once work begins")
+		}
+
+		tm.SetStatus(1, StatusBlocked)
+		tm.SetStatus(1, StatusInProgress)
+		tm.MarkDone(1)
+		got := mockRepo.lastSaved[0]
+		if !got.StartedAt.Equal(*started) {
+			t.Errorf("Expected the first start time to be kept, got %v", got.StartedAt)
+		}
+		if got.CompletedAt == nil || !got.CompletedAt.Equal(got.UpdatedAt) {
+			t.Errorf("Expected completion time %v, got %v", got.UpdatedAt, got.CompletedAt)
+		}
+
+		tm.SetStatus(1, StatusPending)
+		if mockRepo.lastSaved[0].CompletedAt != nil {
+			t.Error("Expected reopening to clear the completion time")
+		}
+	})
+
+	t.Run("Treats cancelled tasks as closed", func(t *testing.T) {
+		task := createTestTask(1, "Task 1", false)
+		task.Status = StatusCancelled
+		if task.IsOpen() || task.IsDone() {
+			t.Error("Cancelled task should be neither open nor done")
+		}
+	})
+}
+
 // TestDelete tests the Delete method
 func TestDelete(t *testing.T) {
-	t.Run("Deletes task successfully", func(t *testing.T) {
+	t.Run("Moves task to the trash", func(t *testing.T) {
 		mockRepo := &MockRepository{
 			tasks: []Task{
 				createTestTask(1, "Task 1", false),
@@ -324,23 +507,54 @@ func TestDelete(t *testing.T) {
 			t.Fatalf("Expected no error, got %v", err)
 		}
 
-		if len(mockRepo.lastSaved) != 2 {
-			t.Errorf("Expected 2 tasks after deletion, got %d", len(mockRepo.lastSaved))
+		if len(mockRepo.lastSaved) != 3 || !mockRepo.lastSaved[1].IsDeleted() {
+			t.Errorf("Expected task 2 to be kept with a deletion time, got %v", mockRepo.lastSaved)
+		}
+
+		tasks, err := tm.List()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
 		}
 
 		// Check Task 1 still exists
 		foundTask1 := false
-		for _, task := range mockRepo.lastSaved {
+		for _, task := range tasks {
 			if task.ID == 1 {
 				foundTask1 = true
 			}
 			if task.ID == 2 {
-				t.Error("Task 2 should have been deleted")
+				t.Error("Task 2 should be hidden from List")
 			}
 		}
 		if !foundTask1 {
 			t.Error("Task 1 should not have been deleted")
 		}
+
+		if found, _ := tm.Search("Task 2"); len(found) != 0 {
+			t.Error("Task 2 should be hidden from Search")
+		}
+		if err := tm.MarkDone(2); err == nil {
+			t.Error("Expected error completing a task in the trash")
+		}
+	})
+
+	t.Run("Does not reuse IDs of trashed tasks", func(t *testing.T) {
+		mockRepo := &MockRepository{
+			tasks: []Task{createTestTask(1, "Task 1", false)},
+		}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		tm.Delete(1)
+		id, err := tm.Add("Task 2")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if id != 2 {
+			t.Errorf("Expected new ID 2, got %d", id)
+		}
 	})
 
 	t.Run("Returns error for non-existent task", func(t *testing.T) {
@@ -377,77 +591,1861 @@ func TestDelete(t *testing.T) {
 	})
 }
 
-// TestSearch tests the Search method
-func TestSearch(t *testing.T) {
-	tasks := []Task{
-		createTestTask(1, "Buy grocery", false),
-		createTestTask(2, "Clean house", false),
-		createTestTask(3, "Go grocery shopping", false),
+// TestTrash tests restoring and purging deleted tasks
+func TestTrash(t *testing.T) {
+	newFamily := func() []Task {
+		tasks := []Task{
+			createTestTask(1, "Parent", false),
+			createTestTask(2, "Child", false),
+			createTestTask(3, "Other", false),
+		}
+		tasks[1].ParentID = 1
+		return tasks
 	}
 
-	t.Run("Finds matching tasks (case-insensitive)", func(t *testing.T) {
-		mockRepo := &MockRepository{tasks: tasks}
+	t.Run("Restores a task with its subtasks", func(t *testing.T) {
+		mockRepo := &MockRepository{tasks: newFamily()}
 		tm, err := NewTaskManager(mockRepo)
 		if err != nil {
 			t.Fatalf("Failed to create TaskManager: %v", err)
 		}
 
-		results, err := tm.Search("grocery")
+		tm.DeleteWithOptions(1, DeleteOptions{Cascade: true})
+		trash, err := tm.Trash()
 		if err != nil {
 			t.Fatalf("Expected no error, got %v", err)
 		}
+		if len(trash) != 2 {
+			t.Fatalf("Expected 2 tasks in the trash, got %d", len(trash))
+		}
 
-		if len(results) != 2 {
-			t.Errorf("Expected 2 results, got %d", len(results))
+		if _, err := tm.Restore(2); err == nil {
+			t.Error("Expected error restoring a subtask before its parent")
 		}
 
-		// Check both grocery-related tasks are found
-		foundTask1 := false
-		foundTask3 := false
-		for _, task := range results {
-			if task.ID == 1 {
-				foundTask1 = true
-			}
-			if task.ID == 3 {
-				foundTask3 = true
-			}
+		restored, err := tm.Restore(1)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
 		}
-		if !foundTask1 || !foundTask3 {
-			t.Error("Expected both grocery tasks to be found")
+		if len(restored) != 2 || restored[0] != 1 || restored[1] != 2 {
+			t.Errorf("Expected tasks [1 2] restored, got %v", restored)
+		}
+		if live, _ := tm.List(); len(live) != 3 {
+			t.Errorf("Expected 3 live tasks, got %d", len(live))
 		}
 	})
 
-	t.Run("Returns empty slice for no matches", func(t *testing.T) {
+	t.Run("Rejects restoring a live task", func(t *testing.T) {
+		mockRepo := &MockRepository{tasks: newFamily()}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		if _, err := tm.Restore(3); err == nil {
+			t.Error("Expected error restoring a task that is not in the trash")
+		}
+	})
+
+	t.Run("Purges only tasks deleted before the cutoff", func(t *testing.T) {
+		mockRepo := &MockRepository{tasks: newFamily()}
+		old := time.Now().AddDate(0, 0, -40)
+		mockRepo.tasks[2].DeletedAt = &old
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		tm.DeleteWithOptions(1, DeleteOptions{Cascade: true})
+		purged, err := tm.Purge(time.Now().AddDate(0, 0, -30))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(purged) != 1 || purged[0] != 3 {
+			t.Errorf("Expected task 3 purged, got %v", purged)
+		}
+		if len(mockRepo.lastSaved) != 2 {
+			t.Errorf("Expected 2 tasks left in storage, got %d", len(mockRepo.lastSaved))
+		}
+	})
+
+	t.Run("Does not reuse the IDs of purged tasks", func(t *testing.T) {
+		tm, err := NewTaskManager(&MockRepository{})
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		tm.Add("one")
+		tm.Add("two")
+		tm.Delete(2)
+		if _, err := tm.Purge(time.Now().Add(time.Second)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		id, err := tm.Add("three")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if id != 3 {
+			t.Errorf("Expected ID 3, got %d", id)
+		}
+	})
+}
+
+// TestArchive tests moving completed tasks to the archive
+func TestArchive(t *testing.T) {
+	newManager := func(t *testing.T) (*TaskManager, *MockRepository, *MockRepository) {
+		old := time.Now().AddDate(0, -2, 0)
+		tasks := []Task{
+			createTestTask(1, "Old report", true),
+			createTestTask(2, "Recent report", true),
+			createTestTask(3, "Open report", false),
+		}
+		tasks[0].UpdatedAt = old
 		mockRepo := &MockRepository{tasks: tasks}
+		archive := &MockRepository{}
 		tm, err := NewTaskManager(mockRepo)
 		if err != nil {
 			t.Fatalf("Failed to create TaskManager: %v", err)
 		}
+		tm.SetArchive(archive)
+		return tm, mockRepo, archive
+	}
 
-		results, err := tm.Search("nonexistent")
+	t.Run("Moves only tasks completed before the cutoff", func(t *testing.T) {
+		tm, mockRepo, archive := newManager(t)
+
+		ids, err := tm.Archive(time.Now().AddDate(0, -1, 0))
 		if err != nil {
 			t.Fatalf("Expected no error, got %v", err)
 		}
+		if len(ids) != 1 || ids[0] != 1 {
+			t.Errorf("Expected task 1 archived, got %v", ids)
+		}
+		if len(mockRepo.lastSaved) != 2 || len(archive.lastSaved) != 1 {
+			t.Errorf("Expected 2 live and 1 archived task, got %d and %d", len(mockRepo.lastSaved), len(archive.lastSaved))
+		}
+	})
 
-		if len(results) != 0 {
-			t.Errorf("Expected 0 results, got %d", len(results))
+	t.Run("Moves all completed tasks without a cutoff", func(t *testing.T) {
+		tm, mockRepo, _ := newManager(t)
+
+		ids, err := tm.Archive(time.Time{})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(ids) != 2 || len(mockRepo.lastSaved) != 1 || mockRepo.lastSaved[0].ID != 3 {
+			t.Errorf("Expected tasks 1 and 2 archived, got %v", ids)
+		}
+
+		archived, err := tm.Archived()
+		if err != nil || len(archived) != 2 {
+			t.Errorf("Expected 2 archived tasks, got %d (%v)", len(archived), err)
 		}
 	})
 
-	t.Run("Handles Load error", func(t *testing.T) {
-		mockRepo := &MockRepository{}
+	t.Run("Keeps done parents with open subtasks", func(t *testing.T) {
+		tasks := []Task{
+			createTestTask(1, "Release", true),
+			createTestTask(2, "Changelog", true),
+			createTestTask(3, "Announcement", false),
+			createTestTask(4, "Retro", true),
+			createTestTask(5, "Notes", true),
+		}
+		tasks[1].ParentID = 1
+		tasks[2].ParentID = 2
+		tasks[4].ParentID = 4
+		mockRepo := &MockRepository{tasks: tasks}
 		tm, err := NewTaskManager(mockRepo)
 		if err != nil {
 			t.Fatalf("Failed to create TaskManager: %v", err)
 		}
+		tm.SetArchive(&MockRepository{})
 
-		// Set load error for the next Load call
-		mockRepo.loadError = errors.New("load failed")
+		ids, err := tm.Archive(time.Time{})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(ids) != 2 || ids[0] != 4 || ids[1] != 5 {
+			t.Errorf("Expected only the finished subtree of task 4 archived, got %v", ids)
+		}
+		if len(mockRepo.lastSaved) != 3 {
+			t.Errorf("Expected tasks 1 to 3 to stay, got %d tasks", len(mockRepo.lastSaved))
+		}
+	})
 
-		_, err = tm.Search("test")
-		if err == nil {
+	t.Run("Keeps the main list when the archive cannot be saved", func(t *testing.T) {
+		tm, mockRepo, archive := newManager(t)
+		archive.saveError = errors.New("disk full")
+
+		if _, err := tm.Archive(time.Time{}); err == nil {
+			t.Fatal("Expected error but got none")
+		}
+		if mockRepo.saveCalled != 0 {
+			t.Error("Main list should not be saved when archiving fails")
+		}
+	})
+
+	t.Run("Searches the archive on request", func(t *testing.T) {
+		tm, _, _ := newManager(t)
+		tm.Archive(time.Time{})
+
+		if found, _ := tm.Search("report"); len(found) != 1 {
+			t.Errorf("Expected 1 live match, got %d", len(found))
+		}
+		found, err := tm.SearchWithOptions("report", SearchOptions{IncludeArchive: true})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(found) != 3 || found[0].Task.ID != 3 {
+			t.Errorf("Expected live match first and 3 in total, got %v", found)
+		}
+	})
+
+	t.Run("Does not reuse archived IDs", func(t *testing.T) {
+		tm, mockRepo, _ := newManager(t)
+		mockRepo.tasks[2].Status = StatusDone
+		tm.Archive(time.Time{})
+
+		id, err := tm.Add("Fresh task")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if id != 4 {
+			t.Errorf("Expected ID 4, got %d", id)
+		}
+	})
+}
+
+// TestLocking tests that changes are made while holding the repository lock
+func TestLocking(t *testing.T) {
+	t.Run("Holds the lock while saving", func(t *testing.T) {
+		repo := &LockingRepository{}
+		tm, err := NewTaskManager(repo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+		tm.SetJournal(&MockJournal{})
+
+		if _, err := tm.Add("Task"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if _, err := tm.Undo(1); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if repo.lockCalls != 2 || repo.locked {
+			t.Errorf("Expected 2 released locks, got %d (still locked: %v)", repo.lockCalls, repo.locked)
+		}
+	})
+
+	t.Run("Fails without saving when the lock is unavailable", func(t *testing.T) {
+		repo := &LockingRepository{lockError: errors.New("timed out waiting for lock")}
+		tm, err := NewTaskManager(repo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		if _, err := tm.Add("Task"); err == nil {
 			t.Fatal("Expected error but got none")
 		}
+		if repo.saveCalled != 0 {
+			t.Error("Tasks should not be saved without the lock")
+		}
+	})
+}
+
+// TestConflicts tests detection and retry of concurrent modifications
+func TestConflicts(t *testing.T) {
+	newManager := func(t *testing.T) (*TaskManager, *MockRepository) {
+		mockRepo := &MockRepository{tasks: []Task{createTestTask(1, "Task 1", false)}}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+		return tm, mockRepo
+	}
+
+	t.Run("Retries idempotent operations", func(t *testing.T) {
+		tm, mockRepo := newManager(t)
+		mockRepo.conflicts = 1
+
+		if err := tm.SetPriority(1, PriorityHigh); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if mockRepo.saveCalled != 2 || mockRepo.tasks[0].Priority != PriorityHigh {
+			t.Errorf("Expected a successful second save, got %d saves", mockRepo.saveCalled)
+		}
+	})
+
+	t.Run("Gives up after repeated conflicts", func(t *testing.T) {
+		tm, mockRepo := newManager(t)
+		mockRepo.conflicts = 100
+
+		err := tm.SetPriority(1, PriorityHigh)
+		if !errors.Is(err, ErrConflict) {
+			t.Fatalf("Expected ErrConflict, got %v", err)
+		}
+		if mockRepo.saveCalled != maxConflictRetries+1 {
+			t.Errorf("Expected %d attempts, got %d", maxConflictRetries+1, mockRepo.saveCalled)
+		}
+	})
+
+	t.Run("Does not retry other operations", func(t *testing.T) {
+		tm, mockRepo := newManager(t)
+		mockRepo.conflicts = 1
+
+		if _, err := tm.Add("Task 2"); !errors.Is(err, ErrConflict) {
+			t.Fatalf("Expected ErrConflict, got %v", err)
+		}
+		if mockRepo.saveCalled != 1 || len(mockRepo.tasks) != 1 {
+			t.Errorf("Expected a single rejected save, got %d saves", mockRepo.saveCalled)
+		}
+	})
+}
+
+// TestSearch tests the Search method
+func TestSearch(t *testing.T) {
+	tasks := []Task{
+		createTestTask(1, "Buy grocery", false),
+		createTestTask(2, "Clean house", false),
+		createTestTask(3, "Go grocery shopping", false),
+	}
+
+	t.Run("Finds matching tasks (case-insensitive)", func(t *testing.T) {
+		mockRepo := &MockRepository{tasks: tasks}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		results, err := tm.Search("grocery")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(results) != 2 {
+			t.Errorf("Expected 2 results, got %d", len(results))
+		}
+
+		// Check both grocery-related tasks are found
+		foundTask1 := false
+		foundTask3 := false
+		for _, task := range results {
+			if task.ID == 1 {
+				foundTask1 = true
+			}
+			if task.ID == 3 {
+				foundTask3 = true
+			}
+		}
+		if !foundTask1 || !foundTask3 {
+			t.Error("Expected both grocery tasks to be found")
+		}
+	})
+
+	t.Run("Returns empty slice for no matches", func(t *testing.T) {
+		mockRepo := &MockRepository{tasks: tasks}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		results, err := tm.Search("nonexistent")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(results) != 0 {
+			t.Errorf("Expected 0 results, got %d", len(results))
+		}
+	})
+
+	t.Run("Handles Load error", func(t *testing.T) {
+		mockRepo := &MockRepository{}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		// Set load error for the next Load call
+		mockRepo.loadError = errors.New("load failed")
+
+		_, err = tm.Search("test")
+		if err == nil {
+			t.Fatal("Expected error but got none")
+		}
+	})
+
+	search := func(t *testing.T, query string, mode SearchMode, descriptions ...string) []SearchResult {
+		var tasks []Task
+		for i, desc := range descriptions {
+			tasks = append(tasks, createTestTask(i+1, desc, false))
+		}
+		tm, err := NewTaskManager(&MockRepository{tasks: tasks})
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		results, err := tm.SearchWithOptions(query, SearchOptions{Mode: mode})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return results
+	}
+	ids := func(results []SearchResult) []int {
+		var ids []int
+		for _, r := range results {
+			ids = append(ids, r.Task.ID)
+		}
+		return ids
+	}
+
+	t.Run("Ranks whole-word matches first", func(t *testing.T) {
+		results := search(t, "log", SearchSubstring, "Check the catalog", "Rotate log files", "Log")
+		if got := ids(results); !reflect.DeepEqual(got, []int{3, 2, 1}) {
+			t.Errorf("Expected order [3 2 1], got %v", got)
+		}
+		if spans := results[1].Spans; !reflect.DeepEqual(spans, []Span{{7, 10}}) {
+			t.Errorf("Expected the match to be at 7-10, got %v", spans)
+		}
+	})
+
+	t.Run("Word mode skips matches inside words", func(t *testing.T) {
+		results := search(t, "log", SearchWord, "Check the catalog", "Rotate log files", "Blog about logs")
+		if got := ids(results); !reflect.DeepEqual(got, []int{2}) {
+			t.Errorf("Expected only task 2, got %v", got)
+		}
+	})
+
+	t.Run("Regex mode", func(t *testing.T) {
+		results := search(t, "fix(ed)? bug", SearchRegex, "Fixed bug in parser", "Fix bug", "Bug fixing")
+		if got := ids(results); !reflect.DeepEqual(got, []int{2, 1}) {
+			t.Errorf("Expected order [2 1], got %v", got)
+		}
+		if spans := results[1].Spans; !reflect.DeepEqual(spans, []Span{{0, 9}}) {
+			t.Errorf("Expected the match to be at 0-9, got %v", spans)
+		}
+
+		tm, _ := NewTaskManager(&MockRepository{})
+		if _, err := tm.SearchWithOptions("fix(", SearchOptions{Mode: SearchRegex}); err == nil {
+			t.Error("Expected error for an invalid regular expression")
+		}
+	})
+
+	t.Run("Fuzzy mode matches subsequences and typos", func(t *testing.T) {
+		results := search(t, "lgin", SearchFuzzy, "Long running migration", "Fix login", "Plugin docs", "Deploy")
+		if got := ids(results); !reflect.DeepEqual(got, []int{2, 3, 1}) {
+			t.Errorf("Expected order [2 3 1], got %v", got)
+		}
+		if spans := results[0].Spans; !reflect.DeepEqual(spans, []Span{{4, 5}, {6, 9}}) {
+			t.Errorf("Expected l and gin to be matched, got %v", spans)
+		}
+
+		results = search(t, "lgoin", SearchFuzzy, "Fix login page", "Deploy")
+		if got := ids(results); !reflect.DeepEqual(got, []int{1}) {
+			t.Errorf("Expected a swapped letter to be tolerated, got %v", got)
+		}
+		if spans := results[0].Spans; !reflect.DeepEqual(spans, []Span{{4, 9}}) {
+			t.Errorf("Expected the word to be matched, got %v", spans)
+		}
+
+		if results := search(t, "xyz", SearchFuzzy, "Fix login page"); len(results) != 0 {
+			t.Errorf("Expected no match, got %v", ids(results))
+		}
+	})
+}
+
+// TestPriority tests priority handling on add, update and sort
+func TestPriority(t *testing.T) {
+	t.Run("Adds task with priority", func(t *testing.T) {
+		mockRepo := &MockRepository{}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		_, err = tm.AddWithOptions("Fix prod", AddOptions{Priority: PriorityUrgent})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if mockRepo.lastSaved[0].Priority != PriorityUrgent {
+			t.Errorf("Expected urgent priority, got %s", mockRepo.lastSaved[0].Priority)
+		}
+	})
+
+	t.Run("Sets priority of existing task", func(t *testing.T) {
+		mockRepo := &MockRepository{
+			tasks: []Task{createTestTask(1, "Task 1", false)},
+		}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		if err := tm.SetPriority(1, PriorityHigh); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if mockRepo.lastSaved[0].Priority != PriorityHigh {
+			t.Errorf("Expected high priority, got %s", mockRepo.lastSaved[0].Priority)
+		}
+	})
+
+	t.Run("Returns error for non-existent task", func(t *testing.T) {
+		mockRepo := &MockRepository{}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		if err := tm.SetPriority(42, PriorityLow); err == nil {
+			t.Fatal("Expected error but got none")
+		}
+	})
+
+	t.Run("Parses and rejects levels", func(t *testing.T) {
+		p, err := ParsePriority("High")
+		if err != nil || p != PriorityHigh {
+			t.Errorf("Expected high, got %s (%v)", p, err)
+		}
+
+		if _, err := ParsePriority("critical"); err == nil {
+			t.Error("Expected error for unknown level")
+		}
+	})
+
+	t.Run("Defaults to none when missing from JSON", func(t *testing.T) {
+		var task Task
+		if err := json.Unmarshal([]byte(`{"id":1,"description":"old"}`), &task); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if task.Priority != PriorityNone {
+			t.Errorf("Expected none, got %s", task.Priority)
+		}
+	})
+
+	t.Run("Sorts by priority then ID", func(t *testing.T) {
+		tasks := []Task{
+			createTestTask(3, "Task 3", false),
+			createTestTask(1, "Task 1", false),
+			createTestTask(2, "Task 2", false),
+		}
+		tasks[0].Priority = PriorityHigh
+		tasks[1].Priority = PriorityLow
+		tasks[2].Priority = PriorityHigh
+
+		SortByPriority(tasks)
+
+		got := []int{tasks[0].ID, tasks[1].ID, tasks[2].ID}
+		want := []int{2, 3, 1}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("Expected order %v, got %v", want, got)
+			}
+		}
+	})
+}
+
+// TestFilter tests due date filtering
+func TestFilter(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	day := func(offset int) *time.Time {
+		d := time.Date(2026, 10, 14+offset, 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+
+	tasks := []Task{
+		createTestTask(1, "Past due", false),
+		createTestTask(2, "Due today", false),
+		createTestTask(3, "Due later", false),
+		createTestTask(4, "Past due but done", true),
+		createTestTask(5, "No due date", false),
+	}
+	tasks[0].DueAt = day(-1)
+	tasks[1].DueAt = day(0)
+	tasks[2].DueAt = day(5)
+	tasks[3].DueAt = day(-3)
+
+	ids := func(tasks []Task) []int {
+		var out []int
+		for _, t := range tasks {
+			out = append(out, t.ID)
+		}
+		return out
+	}
+
+	t.Run("Selects overdue open tasks", func(t *testing.T) {
+		got := ids(FilterTasks(tasks, Filter{Overdue: true, Now: now}))
+		if len(got) != 1 || got[0] != 1 {
+			t.Errorf("Expected [1], got %v", got)
+		}
+	})
+
+	t.Run("Selects tasks due before a date", func(t *testing.T) {
+		got := ids(FilterTasks(tasks, Filter{DueBefore: day(1), Now: now}))
+		if len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 4 {
+			t.Errorf("Expected [1 2 4], got %v", got)
+		}
+	})
+
+	t.Run("Zero filter matches everything", func(t *testing.T) {
+		if got := FilterTasks(tasks, Filter{}); len(got) != len(tasks) {
+			t.Errorf("Expected %d tasks, got %d", len(tasks), len(got))
+		}
+	})
+}
+
+// TestTags tests tag extraction, editing and counting
+func TestTags(t *testing.T) {
+	t.Run("Extracts tags from description", func(t *testing.T) {
+		mockRepo := &MockRepository{}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		if _, err := tm.Add("Fix login +backend +Urgent +backend"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		saved := mockRepo.lastSaved[0]
+		if saved.Description != "Fix login" {
+			t.Errorf("Expected description without tags, got %q", saved.Description)
+		}
+		if len(saved.Tags) != 2 || saved.Tags[0] != "backend" || saved.Tags[1] != "urgent" {
+			t.Errorf("Expected [backend urgent], got %v", saved.Tags)
+		}
+	})
+
+	t.Run("Rejects description made only of tags", func(t *testing.T) {
+		mockRepo := &MockRepository{}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		if _, err := tm.Add("+a +b"); err == nil {
+			t.Fatal("Expected error but got none")
+		}
+	})
+
+	t.Run("Adds and removes tags", func(t *testing.T) {
+		task := createTestTask(1, "Task 1", false)
+		task.Tags = []string{"a", "b"}
+		mockRepo := &MockRepository{tasks: []Task{task}}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		if err := tm.Tag(1, []string{"+c", "a"}, []string{"b"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		tags := mockRepo.lastSaved[0].Tags
+		if len(tags) != 2 || tags[0] != "a" || tags[1] != "c" {
+			t.Errorf("Expected [a c], got %v", tags)
+		}
+	})
+
+	t.Run("Counts open and done tasks per tag", func(t *testing.T) {
+		tasks := []Task{
+			createTestTask(1, "Task 1", false),
+			createTestTask(2, "Task 2", true),
+			createTestTask(3, "Task 3", false),
+		}
+		tasks[0].Tags = []string{"backend"}
+		tasks[1].Tags = []string{"backend", "docs"}
+		mockRepo := &MockRepository{tasks: tasks}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		counts, err := tm.TagCounts()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		want := []TagCount{{Tag: "backend", Open: 1, Done: 1}, {Tag: "docs", Open: 0, Done: 1}}
+		if len(counts) != len(want) || counts[0] != want[0] || counts[1] != want[1] {
+			t.Errorf("Expected %v, got %v", want, counts)
+		}
+	})
+
+	t.Run("Filters by included and excluded tags", func(t *testing.T) {
+		tasks := []Task{
+			createTestTask(1, "Task 1", false),
+			createTestTask(2, "Task 2", false),
+			createTestTask(3, "Task 3", false),
+		}
+		tasks[0].Tags = []string{"backend"}
+		tasks[1].Tags = []string{"backend", "blocked"}
+
+		got := FilterTasks(tasks, Filter{IncludeTags: []string{"backend"}, ExcludeTags: []string{"blocked"}})
+		if len(got) != 1 || got[0].ID != 1 {
+			t.Errorf("Expected only task 1, got %v", got)
+		}
+	})
+}
+
+// TestProjects tests project assignment, filtering and summaries
+func TestProjects(t *testing.T) {
+	t.Run("Moves task to a normalized project", func(t *testing.T) {
+		mockRepo := &MockRepository{
+			tasks: []Task{createTestTask(1, "Task 1", false)},
+		}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		if err := tm.Move(1, "Work.API"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if mockRepo.lastSaved[0].Project != "work.api" {
+			t.Errorf("Expected work.api, got %q", mockRepo.lastSaved[0].Project)
+		}
+	})
+
+	t.Run("Rejects invalid project names", func(t *testing.T) {
+		for _, name := range []string{"work..api", ".work", "work.", "my project"} {
+			if _, err := NormalizeProject(name); err == nil {
+				t.Errorf("Expected error for %q", name)
+			}
+		}
+	})
+
+	t.Run("Filters include sub-projects", func(t *testing.T) {
+		tasks := []Task{
+			createTestTask(1, "Task 1", false),
+			createTestTask(2, "Task 2", false),
+			createTestTask(3, "Task 3", false),
+		}
+		tasks[0].Project = "work"
+		tasks[1].Project = "work.api"
+		tasks[2].Project = "workshop"
+
+		got := FilterTasks(tasks, Filter{Project: "work"})
+		if len(got) != 2 || got[0].ID != 1 || got[1].ID != 2 {
+			t.Errorf("Expected tasks 1 and 2, got %v", got)
+		}
+	})
+
+	t.Run("Builds project tree with counts", func(t *testing.T) {
+		tasks := []Task{
+			createTestTask(1, "Task 1", false),
+			createTestTask(2, "Task 2", true),
+			createTestTask(3, "Task 3", false),
+			createTestTask(4, "Task 4", false),
+		}
+		tasks[0].Project = "work.api.auth"
+		tasks[1].Project = "work.api"
+		tasks[2].Project = "home"
+		mockRepo := &MockRepository{tasks: tasks}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		roots, err := tm.Projects()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(roots) != 2 || roots[0].Path != "home" || roots[1].Path != "work" {
+			t.Fatalf("Expected roots home and work, got %+v", roots)
+		}
+
+		work := roots[1]
+		if work.Open != 1 || work.Done != 1 || work.PercentDone() != 50 {
+			t.Errorf("Expected work 1 open/1 done, got %d/%d", work.Open, work.Done)
+		}
+
+		api := work.Children[0]
+		if api.Path != "work.api" || len(api.Children) != 1 || api.Children[0].Path != "work.api.auth" {
+			t.Errorf("Unexpected sub-tree: %+v", api)
+		}
+	})
+}
+
+// TestSubtasks tests parent/child relationships
+func TestSubtasks(t *testing.T) {
+	newFamily := func() []Task {
+		tasks := []Task{
+			createTestTask(1, "Release", false),
+			createTestTask(2, "Write tests", false),
+			createTestTask(3, "Docs", false),
+			createTestTask(4, "API docs", false),
+		}
+		tasks[1].ParentID = 1
+		tasks[2].ParentID = 1
+		tasks[3].ParentID = 3
+		return tasks
+	}
+
+	t.Run("Adds subtask to existing parent", func(t *testing.T) {
+		mockRepo := &MockRepository{tasks: newFamily()}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		id, err := tm.AddWithOptions("Changelog", AddOptions{ParentID: 1})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got := mockRepo.lastSaved[indexOf(mockRepo.lastSaved, id)].ParentID; got != 1 {
+			t.Errorf("Expected parent 1, got %d", got)
+		}
+
+		if _, err := tm.AddWithOptions("Orphan", AddOptions{ParentID: 99}); err == nil {
+			t.Error("Expected error for missing parent")
+		}
+	})
+
+	t.Run("Refuses cycles", func(t *testing.T) {
+		mockRepo := &MockRepository{tasks: newFamily()}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		if err := tm.SetParent(1, 4); err == nil {
+			t.Error("Expected error for cycle through grandchild")
+		}
+		if err := tm.SetParent(2, 2); err == nil {
+			t.Error("Expected error for self-parent")
+		}
+		if err := tm.SetParent(4, 2); err != nil {
+			t.Errorf("Expected re-parenting to succeed, got %v", err)
+		}
+		if err := tm.SetParent(4, 0); err != nil {
+			t.Errorf("Expected detaching to succeed, got %v", err)
+		}
+	})
+
+	t.Run("Protects parents from deletion unless cascading", func(t *testing.T) {
+		mockRepo := &MockRepository{tasks: newFamily()}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		if err := tm.Delete(1); err == nil {
+			t.Fatal("Expected error deleting task with subtasks")
+		}
+
+		removed, err := tm.DeleteWithOptions(3, DeleteOptions{Cascade: true})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		live, _ := tm.List()
+		if len(removed) != 2 || len(live) != 2 {
+			t.Errorf("Expected tasks 3 and 4 removed, got %v (remaining %d)", removed, len(live))
+		}
+	})
+
+	t.Run("Auto-completes parents when requested", func(t *testing.T) {
+		mockRepo := &MockRepository{tasks: newFamily()}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		if err := tm.MarkDone(2); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if mockRepo.lastSaved[0].IsDone() {
+			t.Error("Parent should not be completed without the option")
+		}
+
+		result, err := tm.MarkDoneWithOptions(4, DoneOptions{CompleteParents: true})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(result.AutoCompleted) != 2 || result.AutoCompleted[0] != 3 || result.AutoCompleted[1] != 1 {
+			t.Errorf("Expected parents 3 and 1 completed, got %v", result.AutoCompleted)
+		}
+	})
+
+	t.Run("Counts done children", func(t *testing.T) {
+		tasks := newFamily()
+		tasks[1].Status = StatusDone
+		total, done := ChildCounts(tasks)
+		if total[1] != 2 || done[1] != 1 || total[3] != 1 || done[3] != 0 {
+			t.Errorf("Unexpected counts: total=%v done=%v", total, done)
+		}
+	})
+}
+
+// TestDependencies tests prerequisites, blocking and next ordering
+func TestDependencies(t *testing.T) {
+	newChain := func() []Task {
+		tasks := []Task{
+			createTestTask(1, "Design", false),
+			createTestTask(2, "Build", false),
+			createTestTask(3, "Ship", false),
+			createTestTask(4, "Email", false),
+		}
+		tasks[0].Priority = PriorityLow
+		tasks[1].Priority = PriorityUrgent
+		tasks[1].DependsOn = []int{1}
+		tasks[2].DependsOn = []int{2}
+		return tasks
+	}
+
+	t.Run("Rejects cycles and unknown tasks", func(t *testing.T) {
+		mockRepo := &MockRepository{tasks: newChain()}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		if err := tm.AddDependencies(1, 3); err == nil {
+			t.Error("Expected error for transitive cycle")
+		}
+		if err := tm.AddDependencies(1, 1); err == nil {
+			t.Error("Expected error for self-dependency")
+		}
+		if err := tm.AddDependencies(1, 99); err == nil {
+			t.Error("Expected error for unknown prerequisite")
+		}
+		if err := tm.AddDependencies(4, 1, 1); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if deps := mockRepo.lastSaved[3].DependsOn; len(deps) != 1 || deps[0] != 1 {
+			t.Errorf("Expected [1], got %v", deps)
+		}
+	})
+
+	t.Run("Refuses to complete blocked tasks unless forced", func(t *testing.T) {
+		mockRepo := &MockRepository{tasks: newChain()}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		if err := tm.MarkDone(2); err == nil {
+			t.Fatal("Expected error completing task with open prerequisite")
+		}
+		if _, err := tm.MarkDoneWithOptions(2, DoneOptions{Force: true}); err != nil {
+			t.Fatalf("Expected forced completion to succeed, got %v", err)
+		}
+	})
+
+	t.Run("Orders plan topologically and next by urgency", func(t *testing.T) {
+		tasks := newChain()
+		due := time.Now().Add(24 * time.Hour)
+		tasks[3].DueAt = &due
+		mockRepo := &MockRepository{tasks: tasks}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		plan, err := tm.Plan()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		position := map[int]int{}
+		for i, step := range plan {
+			position[step.Task.ID] = i
+		}
+		if position[1] > position[2] || position[2] > position[3] {
+			t.Errorf("Prerequisites must come first, got positions %v", position)
+		}
+
+		next, err := tm.Next()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(next) != 2 || next[0].ID != 1 || next[1].ID != 4 {
+			t.Errorf("Expected actionable tasks [1 4], got %v", next)
+		}
+	})
+
+	t.Run("Prerequisites in the trash block until purged", func(t *testing.T) {
+		mockRepo := &MockRepository{tasks: newChain()}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		if err := tm.Delete(1); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := tm.MarkDone(2); err == nil {
+			t.Error("Expected error completing task with a prerequisite in the trash")
+		}
+		next, err := tm.Next()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(next) != 1 || next[0].ID != 4 {
+			t.Errorf("Expected only task 4 to be actionable, got %v", next)
+		}
+
+		// Purging the prerequisite drops the dependency for good
+		if _, err := tm.Purge(time.Now().Add(time.Second)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if deps := mockRepo.lastSaved[0].DependsOn; len(deps) != 0 {
+			t.Errorf("Expected dependency on purged task to be dropped, got %v", deps)
+		}
+		if next, err = tm.Next(); err != nil || len(next) != 2 || next[0].ID != 2 {
+			t.Errorf("Expected task 2 to be actionable after the purge, got %v (%v)", next, err)
+		}
+	})
+}
+
+// TestRecurrence tests recurrence rules and recurring completion
+func TestRecurrence(t *testing.T) {
+	t.Run("Parses rules into canonical form", func(t *testing.T) {
+		cases := map[string]string{
+			"daily":            "daily",
+			"Weekly:FRI":       "weekly:fri",
+			"weekly:fri,mon":   "weekly:mon,fri",
+			"every 2 weeks":    "every 2 weeks",
+			"every fri":        "weekly:fri",
+			"every day":        "daily",
+			"monthly:15":       "monthly:15",
+			"every 3 months:1": "every 3 months:1",
+			"every month":      "monthly",
+			"every months":     "monthly",
+			"every 2 months":   "every 2 months",
+			"every monday":     "weekly:mon",
+			"weekly:tuesday":   "weekly:tue",
+			"yearly:29":        "yearly:29",
+			"weekdays":         "weekly:mon,tue,wed,thu,fri",
+		}
+		for input, want := range cases {
+			r, err := ParseRecurrence(input)
+			if err != nil {
+				t.Errorf("ParseRecurrence(%q) failed: %v", input, err)
+				continue
+			}
+			if r.String() != want {
+				t.Errorf("ParseRecurrence(%q) = %q, want %q", input, r.String(), want)
+			}
+		}
+
+		for _, input := range []string{"", "hourly", "weekly:funday", "weekly:monkey", "every monkey", "monthly:32", "daily:3", "every 0 days"} {
+			if _, err := ParseRecurrence(input); err == nil {
+				t.Errorf("Expected error for %q", input)
+			}
+		}
+	})
+
+	t.Run("Computes next occurrences", func(t *testing.T) {
+		// Wednesday
+		from := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+		cases := map[string]string{
+			"daily":             "2026-10-15",
+			"weekly":            "2026-10-21",
+			"weekly:fri":        "2026-10-16",
+			"weekly:mon":        "2026-10-19",
+			"every 2 weeks:mon": "2026-10-26",
+			"monthly":           "2026-11-14",
+			"monthly:31":        "2026-11-30",
+			"yearly":            "2027-10-14",
+		}
+		for rule, want := range cases {
+			r, err := ParseRecurrence(rule)
+			if err != nil {
+				t.Fatalf("ParseRecurrence(%q) failed: %v", rule, err)
+			}
+			if got := r.Next(from).Format("2006-01-02"); got != want {
+				t.Errorf("%s: Next = %s, want %s", rule, got, want)
+			}
+		}
+	})
+
+	t.Run("Spawns next instance when completed", func(t *testing.T) {
+		due := startOfDay(time.Now())
+		recurring := createTestTask(1, "Weekly report", false)
+		recurring.DueAt = &due
+		recurring.Recur = &Recurrence{Frequency: Weekly, Interval: 1}
+		recurring.Tags = []string{"report"}
+		mockRepo := &MockRepository{tasks: []Task{recurring}}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		result, err := tm.MarkDoneWithOptions(1, DoneOptions{})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.NextID != 2 || len(mockRepo.lastSaved) != 2 {
+			t.Fatalf("Expected next instance with ID 2, got %+v", result)
+		}
+
+		next := mockRepo.lastSaved[1]
+		if next.SeriesID != 1 || next.Status != StatusPending || !next.HasTag("report") {
+			t.Errorf("Next instance not linked correctly: %+v", next)
+		}
+		if !next.DueAt.Equal(due.AddDate(0, 0, 7)) {
+			t.Errorf("Expected due %s, got %s", due.AddDate(0, 0, 7), next.DueAt)
+		}
+	})
+
+	t.Run("Keeps the day of a series starting on the 31st", func(t *testing.T) {
+		mockRepo := &MockRepository{}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		// Far enough ahead that no occurrence is skipped as already past
+		start := time.Date(2099, time.January, 31, 0, 0, 0, 0, time.Local)
+		rule, _ := ParseRecurrence("monthly")
+		id, err := tm.AddWithOptions("Pay rent", AddOptions{DueAt: &start, Recur: rule})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		var dues []string
+		for i := 0; i < 3; i++ {
+			result, err := tm.MarkDoneWithOptions(id, DoneOptions{})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			id = result.NextID
+			next, err := tm.Get(id)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			dues = append(dues, next.DueAt.Format("01-02"))
+		}
+
+		want := []string{"02-28", "03-31", "04-30"}
+		if !reflect.DeepEqual(dues, want) {
+			t.Errorf("Expected due dates %v, got %v", want, dues)
+		}
+	})
+
+	t.Run("Stops a series", func(t *testing.T) {
+		recurring := createTestTask(2, "Standup", false)
+		recurring.SeriesID = 1
+		recurring.Recur = &Recurrence{Frequency: Daily, Interval: 1}
+		mockRepo := &MockRepository{tasks: []Task{recurring}}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		if err := tm.StopRecurrence(2); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if mockRepo.lastSaved[0].Recur != nil {
+			t.Error("Expected recurrence to be cleared")
+		}
+		if err := tm.StopRecurrence(2); err == nil {
+			t.Error("Expected error stopping a non-recurring task")
+		}
+	})
+}
+
+// TestUpdate tests partial updates of existing tasks
+func TestUpdate(t *testing.T) {
+	newTask := func() Task {
+		task := createTestTask(7, "Old description", false)
+		task.CreatedAt = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		due := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+		task.DueAt = &due
+		task.Project = "work"
+		task.Tags = []string{"a"}
+		return task
+	}
+
+	t.Run("Changes only the given fields", func(t *testing.T) {
+		mockRepo := &MockRepository{tasks: []Task{newTask()}}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		desc := "New description +b"
+		priority := PriorityHigh
+		err = tm.Update(7, TaskUpdate{Description: &desc, Priority: &priority, ClearDue: true})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		saved := mockRepo.lastSaved[0]
+		if saved.ID != 7 || !saved.CreatedAt.Equal(newTask().CreatedAt) {
+			t.Error("ID and CreatedAt must be preserved")
+		}
+		if saved.Description != "New description" || saved.Priority != PriorityHigh || saved.DueAt != nil {
+			t.Errorf("Fields not updated: %+v", saved)
+		}
+		if saved.Project != "work" || len(saved.Tags) != 2 {
+			t.Errorf("Untouched fields changed: %+v", saved)
+		}
+	})
+
+	t.Run("Replaces tags and project", func(t *testing.T) {
+		mockRepo := &MockRepository{tasks: []Task{newTask()}}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		tags := []string{"x"}
+		project := ""
+		if err := tm.Update(7, TaskUpdate{Tags: &tags, Project: &project}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		saved := mockRepo.lastSaved[0]
+		if saved.Project != "" || len(saved.Tags) != 1 || saved.Tags[0] != "x" {
+			t.Errorf("Expected project cleared and tags [x], got %+v", saved)
+		}
+	})
+
+	t.Run("Rejects empty and invalid updates", func(t *testing.T) {
+		mockRepo := &MockRepository{tasks: []Task{newTask()}}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		blank := "  "
+		if err := tm.Update(7, TaskUpdate{Description: &blank}); err == nil {
+			t.Error("Expected error for blank description")
+		}
+		if err := tm.Update(7, TaskUpdate{}); err == nil {
+			t.Error("Expected error for empty update")
+		}
+		desc := "x"
+		if err := tm.Update(99, TaskUpdate{Description: &desc}); err == nil {
+			t.Error("Expected error for missing task")
+		}
+		if mockRepo.saveCalled > 0 {
+			t.Error("Save should not be called for rejected updates")
+		}
+	})
+}
+
+// TestJournal tests undo, redo and history
+func TestJournal(t *testing.T) {
+	newManager := func(t *testing.T) (*TaskManager, *MockRepository, *MockJournal) {
+		mockRepo := &MockRepository{}
+		journal := &MockJournal{}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+		tm.SetJournal(journal)
+		return tm, mockRepo, journal
+	}
+
+	t.Run("Records each change", func(t *testing.T) {
+		tm, _, journal := newManager(t)
+		tm.Add("First")
+		tm.Add("Second")
+		tm.MarkDone(1)
+
+		if len(journal.entries) != 3 {
+			t.Fatalf("Expected 3 journal entries, got %d", len(journal.entries))
+		}
+
+		history, err := tm.History(0)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if history[0].Op != "done" || history[0].Seq != 3 {
+			t.Errorf("Expected newest entry first, got #%d %q", history[0].Seq, history[0].Op)
+		}
+		if ids := history[0].TaskIDs(); len(ids) != 1 || ids[0] != 1 {
+			t.Errorf("Expected done entry to touch task 1, got %v", ids)
+		}
+	})
+
+	t.Run("Undo and redo a delete", func(t *testing.T) {
+		tm, mockRepo, _ := newManager(t)
+		tm.Add("Keep me")
+		tm.Delete(1)
+
+		undone, err := tm.Undo(1)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(undone) != 1 || undone[0].Op != "delete" {
+			t.Errorf("Expected the delete to be undone, got %v", undone)
+		}
+		if len(mockRepo.tasks) != 1 || mockRepo.tasks[0].IsDeleted() {
+			t.Errorf("Expected task to be restored, got %v", mockRepo.tasks)
+		}
+
+		if _, err := tm.Redo(1); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !mockRepo.tasks[0].IsDeleted() {
+			t.Errorf("Expected task to be deleted again, got %v", mockRepo.tasks)
+		}
+	})
+
+	t.Run("Undo several changes", func(t *testing.T) {
+		tm, mockRepo, _ := newManager(t)
+		tm.Add("Task")
+		tm.SetPriority(1, PriorityHigh)
+		tm.MarkDone(1)
+
+		if _, err := tm.Undo(2); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		got := mockRepo.tasks[0]
+		if got.Status != StatusPending || got.Priority != PriorityNone {
+			t.Errorf("Expected pending task without priority, got %s/%s", got.Status, got.Priority)
+		}
+	})
+
+	t.Run("New change clears redo", func(t *testing.T) {
+		tm, _, _ := newManager(t)
+		tm.Add("First")
+		tm.Undo(1)
+		tm.Add("Second")
+
+		if _, err := tm.Redo(1); err == nil {
+			t.Error("Expected error redoing after a new change")
+		}
+	})
+
+	t.Run("Nothing to undo", func(t *testing.T) {
+		tm, _, _ := newManager(t)
+		if _, err := tm.Undo(1); err == nil {
+			t.Error("Expected error with an empty journal")
+		}
+	})
+
+	t.Run("Refuses to undo over later edits", func(t *testing.T) {
+		tm, mockRepo, journal := newManager(t)
+		tm.Add("Task")
+		tm.SetPriority(1, PriorityHigh)
+
+		// Simulate a change made without going through the journal
+		mockRepo.tasks[0].Description = "Edited elsewhere"
+
+		if _, err := tm.Undo(1); err == nil {
+			t.Error("Expected error undoing a task changed since")
+		}
+		if journal.entries[1].Undone {
+			t.Error("Entry should not be marked undone after a failed undo")
+		}
+	})
+}
+
+// TestChangeLog tests recording field-level changes and reading a task's log
+func TestChangeLog(t *testing.T) {
+	newManager := func(t *testing.T) (*TaskManager, *MockRepository, *MockChangeLog) {
+		mockRepo := &MockRepository{}
+		changeLog := &MockChangeLog{}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+		tm.SetJournal(&MockJournal{})
+		tm.SetChangeLog(changeLog)
+		tm.SetActor("alice")
+		return tm, mockRepo, changeLog
+	}
+
+	kinds := func(changes []Change) []ChangeKind {
+		var kinds []ChangeKind
+		for _, c := range changes {
+			kinds = append(kinds, c.Kind)
+		}
+		return kinds
+	}
+
+	t.Run("Records the lifecycle of a task", func(t *testing.T) {
+		tm, _, _ := newManager(t)
+		tm.Add("Write report")
+		tm.SetPriority(1, PriorityHigh)
+		tm.MarkDone(1)
+		tm.SetStatus(1, StatusPending)
+		tm.Delete(1)
+		tm.Restore(1)
+
+		log, err := tm.Log(1)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		want := []ChangeKind{ChangeCreated, ChangeUpdated, ChangeCompleted, ChangeReopened, ChangeDeleted, ChangeRestored}
+		if fmt.Sprint(kinds(log.Changes)) != fmt.Sprint(want) {
+			t.Fatalf("Expected %v, got %v", want, kinds(log.Changes))
+		}
+
+		priority := log.Changes[1]
+		if priority.Op != "priority high" || priority.By != "alice" {
+			t.Errorf("Expected priority change by alice, got %q by %q", priority.Op, priority.By)
+		}
+		if len(priority.Fields) != 1 || priority.Fields[0] != (FieldChange{Field: "priority", New: "high"}) {
+			t.Errorf("Expected only the priority field to change, got %+v", priority.Fields)
+		}
+		if log.Task == nil || log.Task.Description != "Write report" {
+			t.Errorf("Expected the current task, got %+v", log.Task)
+		}
+	})
+
+	t.Run("Keeps the history of a purged task apart from new tasks", func(t *testing.T) {
+		tm, _, _ := newManager(t)
+		tm.Add("one")
+		tm.Add("two")
+		tm.Delete(2)
+		tm.Purge(time.Now().Add(time.Second))
+		id, _ := tm.Add("three")
+
+		purged, err := tm.Log(2)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		want := []ChangeKind{ChangeCreated, ChangeDeleted, ChangeRemoved}
+		if fmt.Sprint(kinds(purged.Changes)) != fmt.Sprint(want) || purged.Task != nil {
+			t.Errorf("Expected %v for the purged task, got %v (task %+v)", want, kinds(purged.Changes), purged.Task)
+		}
+
+		added, _ := tm.Log(id)
+		if id == 2 || len(added.Changes) != 1 || added.Task.Description != "three" {
+			t.Errorf("Expected a fresh history for task %d, got %v", id, kinds(added.Changes))
+		}
+	})
+
+	t.Run("Records undo as a change of its own", func(t *testing.T) {
+		tm, _, _ := newManager(t)
+		renamed := "Renamed"
+		tm.Add("Task")
+		tm.Update(1, TaskUpdate{Description: &renamed})
+		tm.Undo(1)
+
+		log, _ := tm.Log(1)
+		last := log.Changes[len(log.Changes)-1]
+		if last.Op != "undo" || len(last.Fields) != 1 || last.Fields[0].Old != "Renamed" || last.Fields[0].New != "Task" {
+			t.Errorf("Expected undo to rename the task back, got %+v", last)
+		}
+	})
+
+	t.Run("Keeps the log of purged tasks", func(t *testing.T) {
+		tm, _, _ := newManager(t)
+		tm.Add("Task")
+		tm.Delete(1)
+		tm.Purge(time.Now().Add(time.Second))
+
+		log, err := tm.Log(1)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if log.Task != nil || log.Changes[len(log.Changes)-1].Kind != ChangeRemoved {
+			t.Errorf("Expected a purged task ending in removal, got %+v", log)
+		}
+	})
+
+	t.Run("Records archiving", func(t *testing.T) {
+		tm, _, changeLog := newManager(t)
+		tm.SetArchive(&MockRepository{})
+		tm.Add("Task")
+		tm.MarkDone(1)
+		tm.Archive(time.Time{})
+
+		log, err := tm.Log(1)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !log.Archived || changeLog.changes[len(changeLog.changes)-1].Kind != ChangeArchived {
+			t.Errorf("Expected the archived task, got %+v", log)
+		}
+	})
+
+	t.Run("Unknown task", func(t *testing.T) {
+		tm, _, _ := newManager(t)
+		if _, err := tm.Log(42); err == nil {
+			t.Error("Expected error for a task that never existed")
+		}
+	})
+}
+
+// TestCycleTimes tests lead and cycle time statistics of completed tasks
+func TestCycleTimes(t *testing.T) {
+	base := time.Date(2026, 9, 1, 9, 0, 0, 0, time.UTC)
+	at := func(hours int) *time.Time {
+		t := base.Add(time.Duration(hours) * time.Hour)
+		return &t
+	}
+	completed := func(id int, project string, tags []string, started *time.Time, done *time.Time) Task {
+		t := createTestTask(id, "Task", true)
+		t.CreatedAt = base
+		t.Project = project
+		t.Tags = tags
+		t.StartedAt = started
+		t.CompletedAt = done
+		return t
+	}
+
+	mockRepo := &MockRepository{tasks: []Task{
+		completed(1, "web", []string{"bug"}, at(2), at(4)),
+		completed(2, "web", []string{"bug", "ui"}, at(10), at(20)),
+		completed(3, "api", nil, nil, at(30)),
+		completed(4, "web", nil, at(1), at(100)),
+		completed(5, "api", nil, nil, nil),
+		createTestTask(6, "Open", false),
+	}}
+	archive := &MockRepository{tasks: []Task{completed(7, "api", nil, at(40), at(50))}}
+
+	tm, err := NewTaskManager(mockRepo)
+	if err != nil {
+		t.Fatalf("Failed to create TaskManager: %v", err)
+	}
+	tm.SetArchive(archive)
+
+	t.Run("All tasks", func(t *testing.T) {
+		stats, err := tm.CycleTimes(CycleTimeOptions{})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(stats) != 1 || stats[0].Group != "all" {
+			t.Fatalf("Expected a single group, got %+v", stats)
+		}
+
+		// Lead times 4h, 20h, 30h, 50h, 100h; cycle times 2h, 10h, 10h, 99h
+		lead, cycle := stats[0].Lead, stats[0].Cycle
+		if lead.Count != 5 || lead.Median != 30*time.Hour || lead.P90 != 80*time.Hour {
+			t.Errorf("Unexpected lead times %+v", lead)
+		}
+		if cycle.Count != 4 || cycle.Median != 10*time.Hour {
+			t.Errorf("Unexpected cycle times %+v", cycle)
+		}
+	})
+
+	t.Run("By project", func(t *testing.T) {
+		stats, _ := tm.CycleTimes(CycleTimeOptions{By: GroupByProject})
+		if len(stats) != 2 || stats[0].Group != "api" || stats[0].Lead.Count != 2 || stats[0].Cycle.Count != 1 {
+			t.Errorf("Unexpected project stats %+v", stats)
+		}
+	})
+
+	t.Run("By tag", func(t *testing.T) {
+		stats, _ := tm.CycleTimes(CycleTimeOptions{By: GroupByTag})
+		groups := map[string]int{}
+		for _, s := range stats {
+			groups[s.Group] = s.Lead.Count
+		}
+		if groups["bug"] != 2 || groups["ui"] != 1 || groups[""] != 3 {
+			t.Errorf("Unexpected tag stats %v", groups)
+		}
+	})
+
+	t.Run("Date range", func(t *testing.T) {
+		stats, _ := tm.CycleTimes(CycleTimeOptions{From: *at(20), To: *at(50)})
+		if len(stats) != 1 || stats[0].Lead.Count != 2 {
+			t.Errorf("Expected tasks 2 and 3 only, got %+v", stats)
+		}
+	})
+
+	t.Run("Invalid grouping", func(t *testing.T) {
+		if _, err := tm.CycleTimes(CycleTimeOptions{By: "owner"}); err == nil {
+			t.Error("Expected error for an unknown grouping")
+		}
+	})
+}
+
+// TestTimeTracking tests the timer and the timesheet built from tracked time
+func TestTimeTracking(t *testing.T) {
+	newManager := func(t *testing.T, tasks ...Task) (*TaskManager, *MockRepository) {
+		mockRepo := &MockRepository{tasks: tasks}
+		tm, err := NewTaskManager(mockRepo)
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+		return tm, mockRepo
+	}
+
+	t.Run("Only one timer runs at a time", func(t *testing.T) {
+		tm, mockRepo := newManager(t, createTestTask(1, "First", false), createTestTask(2, "Second", false))
+
+		if stopped, err := tm.StartTimer(1); err != nil || stopped != 0 {
+			t.Fatalf("Expected timer to start, got %d (%v)", stopped, err)
+		}
+		if mockRepo.tasks[0].Status != StatusInProgress {
+			t.Errorf("Expected the task to be in progress, got %s", mockRepo.tasks[0].Status)
+		}
+		if _, err := tm.StartTimer(1); err == nil {
+			t.Error("Expected error starting a running timer again")
+		}
+
+		stopped, err := tm.StartTimer(2)
+		if err != nil || stopped != 1 {
+			t.Fatalf("Expected the timer of task 1 to be stopped, got %d (%v)", stopped, err)
+		}
+		if _, running := mockRepo.tasks[0].Running(); running {
+			t.Error("Task 1 should no longer be tracked")
+		}
+
+		running, err := tm.RunningTimer()
+		if err != nil || running == nil || running.ID != 2 {
+			t.Fatalf("Expected task 2 to be tracked, got %v (%v)", running, err)
+		}
+
+		task, tracked, err := tm.StopTimer()
+		if err != nil || task.ID != 2 || tracked.End == nil {
+			t.Fatalf("Expected the timer of task 2 to stop, got %d %+v (%v)", task.ID, tracked, err)
+		}
+		if _, _, err := tm.StopTimer(); err == nil {
+			t.Error("Expected error stopping when no timer runs")
+		}
+	})
+
+	t.Run("Closing a task stops its timer", func(t *testing.T) {
+		tm, mockRepo := newManager(t, createTestTask(1, "Task", false))
+		tm.StartTimer(1)
+		tm.MarkDone(1)
+
+		if _, running := mockRepo.tasks[0].Running(); running {
+			t.Error("Expected the timer to stop when the task is done")
+		}
+		if _, err := tm.StartTimer(1); err == nil {
+			t.Error("Expected error tracking a done task")
+		}
+	})
+
+	t.Run("Deleting a task stops its timer", func(t *testing.T) {
+		tm, mockRepo := newManager(t, createTestTask(1, "Task", false))
+		tm.StartTimer(1)
+		tm.Delete(1)
+		deletedAt := *mockRepo.tasks[0].DeletedAt
+
+		if _, err := tm.Restore(1); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if _, running := mockRepo.tasks[0].Running(); running {
+			t.Fatal("Expected the timer to stop when the task is deleted")
+		}
+		if end := mockRepo.tasks[0].Tracked[0].End; !end.Equal(deletedAt) {
+			t.Errorf("Expected tracking to end at deletion %s, got %s", deletedAt, end)
+		}
+	})
+
+	t.Run("Restoring closes a timer left running in the trash", func(t *testing.T) {
+		deletedAt := time.Now().Add(-48 * time.Hour)
+		trashed := createTestTask(1, "Task", false)
+		trashed.DeletedAt = &deletedAt
+		trashed.Tracked = []Interval{{Start: deletedAt.Add(-time.Hour)}}
+		tm, mockRepo := newManager(t, trashed)
+
+		tm.Restore(1)
+		if got := mockRepo.tasks[0].TrackedTime(time.Now()); got != time.Hour {
+			t.Errorf("Expected the hour before deletion only, got %s", got)
+		}
+	})
+
+	t.Run("Timesheet splits time by day", func(t *testing.T) {
+		day := time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local)
+		at := func(days, hours, minutes int) time.Time {
+			return day.AddDate(0, 0, days).Add(time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute)
+		}
+		interval := func(start, end time.Time) Interval {
+			return Interval{Start: start, End: &end}
+		}
+
+		first := createTestTask(1, "Late night", false)
+		first.Project = "web"
+		first.Tracked = []Interval{
+			interval(at(0, 23, 0), at(1, 1, 30)),
+			interval(at(1, 9, 0), at(1, 9, 45)),
+		}
+		second := createTestTask(2, "Old", false)
+		second.Tracked = []Interval{interval(at(-3, 10, 0), at(-3, 12, 0))}
+		archived := createTestTask(3, "Archived", true)
+		archived.Tracked = []Interval{interval(at(2, 8, 0), at(2, 8, 30))}
+
+		tm, _ := newManager(t, first, second)
+		tm.SetArchive(&MockRepository{tasks: []Task{archived}})
+
+		entries, err := tm.Timesheet(day, day.AddDate(0, 0, 7))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		want := []struct {
+			day      int
+			id       int
+			duration time.Duration
+		}{
+			{0, 1, time.Hour},
+			{1, 1, 2*time.Hour + 15*time.Minute},
+			{2, 3, 30 * time.Minute},
+		}
+		if len(entries) != len(want) {
+			t.Fatalf("Expected %d entries, got %+v", len(want), entries)
+		}
+		for i, w := range want {
+			e := entries[i]
+			if !e.Day.Equal(day.AddDate(0, 0, w.day)) || e.TaskID != w.id || e.Duration != w.duration {
+				t.Errorf("Entry %d: expected task %d on day %d for %v, got task %d on %s for %v",
+					i, w.id, w.day, w.duration, e.TaskID, e.Day.Format("2006-01-02"), e.Duration)
+			}
+		}
+	})
+
+	t.Run("Pomodoros are logged as tracked time", func(t *testing.T) {
+		tm, mockRepo := newManager(t, createTestTask(1, "Focus", false), createTestTask(2, "Other", false))
+		tm.StartTimer(2)
+
+		stopped, err := tm.StartPomodoro(1)
+		if err != nil || stopped != 2 {
+			t.Fatalf("Expected the timer of task 2 to be stopped, got %d (%v)", stopped, err)
+		}
+		if mockRepo.tasks[0].Status != StatusInProgress {
+			t.Errorf("Expected the task to be in progress, got %s", mockRepo.tasks[0].Status)
+		}
+
+		start := time.Now().Add(-time.Hour)
+		if err := tm.LogPomodoro(1, start, start.Add(25*time.Minute), false); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := tm.LogPomodoro(1, start.Add(30*time.Minute), start.Add(40*time.Minute), true); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := tm.LogPomodoro(1, start, start, false); err == nil {
+			t.Error("Expected error logging an empty pomodoro")
+		}
+
+		task := mockRepo.tasks[0]
+		if completed, interrupted := task.Pomodoros(); completed != 1 || interrupted != 1 {
+			t.Errorf("Expected 1 completed and 1 interrupted pomodoro, got %d and %d", completed, interrupted)
+		}
+		if got := task.TrackedTime(time.Now()); got != 35*time.Minute {
+			t.Errorf("Expected 35m tracked, got %v", got)
+		}
+
+		tm.MarkDone(1)
+		if _, err := tm.StartPomodoro(1); err == nil {
+			t.Error("Expected error starting a pomodoro on a done task")
+		}
+	})
+}
+
+// TestEstimates tests parsing estimates and comparing them with actual effort
+func TestEstimates(t *testing.T) {
+	t.Run("Parses durations and points", func(t *testing.T) {
+		valid := map[string]string{
+			"3h":     "3h",
+			"90m":    "1h30m",
+			"1h 15m": "1h15m",
+			"5pt":    "5pt",
+			"2.5pts": "2.5pt",
+			"3 SP":   "3pt",
+		}
+		for input, want := range valid {
+			e, err := ParseEstimate(input)
+			if err != nil {
+				t.Errorf("ParseEstimate(%q) failed: %v", input, err)
+				continue
+			}
+			if e.String() != want {
+				t.Errorf("ParseEstimate(%q) = %q, want %q", input, e.String(), want)
+			}
+		}
+
+		for _, input := range []string{"", "soon", "0pt", "-2pt", "8", "90", "30s", "-1h", "nanpt"} {
+			if _, err := ParseEstimate(input); err == nil {
+				t.Errorf("Expected ParseEstimate(%q) to fail", input)
+			}
+		}
+	})
+
+	t.Run("Edits and clears the estimate", func(t *testing.T) {
+		mockRepo := &MockRepository{tasks: []Task{createTestTask(1, "Task", false)}}
+		tm, _ := NewTaskManager(mockRepo)
+
+		estimate, _ := ParseEstimate("2h")
+		if err := tm.Update(1, TaskUpdate{Estimate: estimate}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if e := mockRepo.tasks[0].Estimate; e == nil || e.Duration != 2*time.Hour {
+			t.Fatalf("Expected a 2h estimate, got %v", e)
+		}
+		if err := tm.Update(1, TaskUpdate{ClearEstimate: true}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if mockRepo.tasks[0].Estimate != nil {
+			t.Errorf("Expected the estimate to be cleared, got %v", mockRepo.tasks[0].Estimate)
+		}
+	})
+
+	t.Run("Reports accuracy per project and overruns", func(t *testing.T) {
+		end := time.Now().Add(-time.Hour)
+		done := func(id int, project, estimate string, tracked, cycle time.Duration) Task {
+			task := createTestTask(id, fmt.Sprintf("Task %d", id), true)
+			task.Project = project
+			task.Estimate, _ = ParseEstimate(estimate)
+			if tracked > 0 {
+				start := end.Add(-tracked)
+				task.Tracked = []Interval{{Start: start, End: &end}}
+			}
+			started := end.Add(-cycle)
+			task.StartedAt, task.CompletedAt = &started, &end
+			return task
+		}
+
+		tasks := []Task{
+			done(1, "web", "2h", 2*time.Hour, 24*time.Hour),
+			done(2, "web", "1h", 3*time.Hour, 4*time.Hour),
+			done(3, "api", "1pt", time.Hour, time.Hour),
+			done(4, "api", "2pt", 2*time.Hour, 2*time.Hour),
+			done(5, "api", "1pt", 3*time.Hour, 3*time.Hour),
+			done(6, "api", "1h", 0, time.Hour),
+			createTestTask(7, "No estimate", true),
+		}
+		open := done(8, "web", "1h", 5*time.Hour, time.Hour)
+		open.Status = StatusInProgress
+		tasks = append(tasks, open)
+
+		tm, err := NewTaskManager(&MockRepository{tasks: tasks})
+		if err != nil {
+			t.Fatalf("Failed to create TaskManager: %v", err)
+		}
+
+		report, err := tm.Estimates(EstimateOptions{Threshold: 0.5})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(report.Projects) != 2 {
+			t.Fatalf("Expected 2 projects, got %+v", report.Projects)
+		}
+		api, web := report.Projects[0], report.Projects[1]
+		if api.Project != "api" || api.Tasks != 0 || api.PointTasks != 3 || api.PerPoint != time.Hour || api.Ratio != 0 {
+			t.Errorf("Expected api with 3 point tasks at 1h per point, got %+v", api)
+		}
+		if web.Project != "web" || web.Tasks != 2 || web.Ratio != 5.0/3 {
+			t.Errorf("Expected web with 2 tasks and ratio 1.67, got %+v", web)
+		}
+
+		// Task 5 took three times the project's pace, but points have no fixed length
+		if len(report.Overruns) != 1 || report.Overruns[0].Task.ID != 2 {
+			t.Fatalf("Expected only task 2 to overrun, got %+v", report.Overruns)
+		}
+		if report.Overruns[0].Ratio != 3 {
+			t.Errorf("Expected task 2 to have taken 3x its estimate, got %v", report.Overruns[0].Ratio)
+		}
+
+		report, err = tm.Estimates(EstimateOptions{Against: AgainstCycle, Threshold: 10})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(report.Overruns) != 1 || report.Overruns[0].Task.ID != 1 {
+			t.Errorf("Expected only task 1 to overrun its estimate in cycle time, got %+v", report.Overruns)
+		}
+
+		if _, err := tm.Estimates(EstimateOptions{Against: "vibes"}); err == nil {
+			t.Error("Expected error for an unknown effort measure")
+		}
 	})
 }
 
@@ -493,10 +2491,10 @@ func TestTaskManagerIntegration(t *testing.T) {
 	}
 
 	for _, task := range tasks {
-		if task.ID == id1 && !task.Done {
+		if task.ID == id1 && !task.IsDone() {
 			t.Error("Task 1 should be marked as done")
 		}
-		if task.ID == id2 && task.Done {
+		if task.ID == id2 && task.IsDone() {
 			t.Error("Task 2 should not be marked as done")
 		}
 	}
@@ -0,0 +1,306 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Interval is a stretch of time tracked on a task. End is nil while the
+// timer is running.
+type Interval struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
+	// Pomodoro marks time logged by a pomodoro session; Interrupted marks a
+	// session that was stopped before its work period ended.
+	Pomodoro    bool `json:"pomodoro,omitempty"`
+	Interrupted bool `json:"interrupted,omitempty"`
+}
+
+// Duration returns the length of the interval, counting a running one up to now.
+func (iv Interval) Duration(now time.Time) time.Duration {
+	end := now
+	if iv.End != nil {
+		end = *iv.End
+	}
+	return max(end.Sub(iv.Start), 0)
+}
+
+// TimeEntry is the time tracked on one task during one day.
+type TimeEntry struct {
+	// Day is midnight at the start of the day, in local time.
+	Day         time.Time
+	TaskID      int
+	Description string
+	Project     string
+	Duration    time.Duration
+}
+
+// Running returns the interval of the task's running timer, if it has one.
+func (t Task) Running() (Interval, bool) {
+	if n := len(t.Tracked); n > 0 && t.Tracked[n-1].End == nil {
+		return t.Tracked[n-1], true
+	}
+	return Interval{}, false
+}
+
+// TrackedTime returns the total time tracked on the task up to now.
+func (t Task) TrackedTime(now time.Time) time.Duration {
+	var total time.Duration
+	for _, iv := range t.Tracked {
+		total += iv.Duration(now)
+	}
+	return total
+}
+
+// Pomodoros counts the task's completed and interrupted pomodoro sessions.
+func (t Task) Pomodoros() (completed, interrupted int) {
+	for _, iv := range t.Tracked {
+		switch {
+		case iv.Pomodoro && iv.Interrupted:
+			interrupted++
+		case iv.Pomodoro:
+			completed++
+		}
+	}
+	return completed, interrupted
+}
+
+// stopTimer ends the task's running timer, if any, at now.
+func (t *Task) stopTimer(now time.Time) {
+	if n := len(t.Tracked); n > 0 && t.Tracked[n-1].End == nil {
+		end := now
+		t.Tracked[n-1].End = &end
+	}
+}
+
+// StartTimer starts tracking time on the task with the given ID. Only one timer
+// runs at a time, so a timer running on another task is stopped first and that
+// task's ID is returned. A pending task is moved to in-progress.
+func (tm *TaskManager) StartTimer(id int) (int, error) {
+	var stopped int
+
+	err := tm.mutate("track start", func(tasks []Task) ([]Task, error) {
+		i := indexOf(tasks, id)
+		if i < 0 {
+			return nil, fmt.Errorf("task with ID %d not found", id)
+		}
+		if !tasks[i].IsOpen() {
+			return nil, fmt.Errorf("task %d is %s; reopen it to track time", id, tasks[i].Status)
+		}
+		if _, ok := tasks[i].Running(); ok {
+			return nil, fmt.Errorf("task %d is already being tracked", id)
+		}
+
+		now := time.Now()
+		stopped = beginWork(tasks, i, now)
+		tasks[i].Tracked = append(tasks[i].Tracked, Interval{Start: now})
+		tasks[i].UpdatedAt = now
+
+		return tasks, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return stopped, nil
+}
+
+// StartPomodoro prepares the task with the given ID for a pomodoro session the
+// way StartTimer does, stopping any running timer and returning its task's ID,
+// but without starting a timer of its own: the session logs its time with
+// LogPomodoro once each work period is over.
+func (tm *TaskManager) StartPomodoro(id int) (int, error) {
+	var stopped int
+
+	err := tm.mutate("pomodoro start", func(tasks []Task) ([]Task, error) {
+		i := indexOf(tasks, id)
+		if i < 0 {
+			return nil, fmt.Errorf("task with ID %d not found", id)
+		}
+		if !tasks[i].IsOpen() {
+			return nil, fmt.Errorf("task %d is %s; reopen it to track time", id, tasks[i].Status)
+		}
+
+		stopped = beginWork(tasks, i, time.Now())
+		return tasks, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return stopped, nil
+}
+
+// LogPomodoro records a pomodoro work period from start to end on the task with
+// the given ID. Interrupted marks a session cut short.
+func (tm *TaskManager) LogPomodoro(id int, start, end time.Time, interrupted bool) error {
+	if !end.After(start) {
+		return fmt.Errorf("pomodoro must end after it starts")
+	}
+
+	return tm.mutate("pomodoro", func(tasks []Task) ([]Task, error) {
+		i := indexOf(tasks, id)
+		if i < 0 {
+			return nil, fmt.Errorf("task with ID %d not found", id)
+		}
+
+		tasks[i].Tracked = append(tasks[i].Tracked, Interval{
+			Start:       start,
+			End:         &end,
+			Pomodoro:    true,
+			Interrupted: interrupted,
+		})
+		tasks[i].UpdatedAt = time.Now()
+
+		return tasks, nil
+	})
+}
+
+// StopTimer stops the running timer and returns the task it was running on
+// together with the interval that was just tracked.
+func (tm *TaskManager) StopTimer() (Task, Interval, error) {
+	var stopped Task
+	var tracked Interval
+
+	err := tm.mutate("track stop", func(tasks []Task) ([]Task, error) {
+		r := runningIndex(tasks)
+		if r < 0 {
+			return nil, fmt.Errorf("no timer is running")
+		}
+
+		now := time.Now()
+		tasks[r].stopTimer(now)
+		tasks[r].UpdatedAt = now
+
+		stopped = tasks[r].Clone()
+		tracked = stopped.Tracked[len(stopped.Tracked)-1]
+		return tasks, nil
+	})
+	if err != nil {
+		return Task{}, Interval{}, err
+	}
+
+	return stopped, tracked, nil
+}
+
+// RunningTimer returns the task whose timer is running, or nil if none is.
+func (tm *TaskManager) RunningTimer() (*Task, error) {
+	tasks, err := tm.loadLive()
+	if err != nil {
+		return nil, err
+	}
+
+	if r := runningIndex(tasks); r >= 0 {
+		return &tasks[r], nil
+	}
+	return nil, nil
+}
+
+// Timesheet returns the time tracked between from and to, split by local day
+// and task and sorted by day, project and task ID. Archived tasks are included,
+// and a running timer counts up to now.
+func (tm *TaskManager) Timesheet(from, to time.Time) ([]TimeEntry, error) {
+	tasks, err := tm.loadLive()
+	if err != nil {
+		return nil, err
+	}
+	if tm.archive != nil {
+		archived, err := tm.Archived()
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, archived...)
+	}
+
+	type key struct {
+		day time.Time
+		id  int
+	}
+	now := time.Now()
+	entries := map[key]*TimeEntry{}
+
+	for _, t := range tasks {
+		for _, iv := range t.Tracked {
+			start, end := iv.Start, now
+			if iv.End != nil {
+				end = *iv.End
+			}
+			start, end = latest(start, from), earliest(end, to)
+
+			// Split the interval at local midnights
+			for start.Before(end) {
+				day := startOfDay(start.Local())
+				next := day.AddDate(0, 0, 1)
+				part := earliest(end, next).Sub(start)
+
+				k := key{day, t.ID}
+				e, ok := entries[k]
+				if !ok {
+					e = &TimeEntry{Day: day, TaskID: t.ID, Description: t.Description, Project: t.Project}
+					entries[k] = e
+				}
+				e.Duration += part
+				start = next
+			}
+		}
+	}
+
+	result := make([]TimeEntry, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, *e)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if !a.Day.Equal(b.Day) {
+			return a.Day.Before(b.Day)
+		}
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		return a.TaskID < b.TaskID
+	})
+
+	return result, nil
+}
+
+// beginWork stops the running timer, if any, and moves tasks[i] to in-progress
+// if it is pending. It returns the ID of the task whose timer was stopped, or 0.
+func beginWork(tasks []Task, i int, now time.Time) int {
+	stopped := 0
+	if r := runningIndex(tasks); r >= 0 {
+		tasks[r].stopTimer(now)
+		tasks[r].UpdatedAt = now
+		stopped = tasks[r].ID
+	}
+
+	if tasks[i].Status == StatusPending {
+		tasks[i].setStatus(StatusInProgress, now)
+	}
+	return stopped
+}
+
+// runningIndex returns the index of the task with a running timer, or -1.
+// Tasks in the trash are not considered.
+func runningIndex(tasks []Task) int {
+	for i, t := range tasks {
+		if _, ok := t.Running(); ok && !t.IsDeleted() {
+			return i
+		}
+	}
+	return -1
+}
+
+func earliest(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,132 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Trash returns the tasks in the trash, most recently deleted first.
+func (tm *TaskManager) Trash() ([]Task, error) {
+	tasks, _, err := tm.repo.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var trashed []Task
+	for _, t := range tasks {
+		if t.IsDeleted() {
+			trashed = append(trashed, t)
+		}
+	}
+
+	sort.SliceStable(trashed, func(i, j int) bool {
+		return trashed[i].DeletedAt.After(*trashed[j].DeletedAt)
+	})
+	return trashed, nil
+}
+
+// Restore takes the task with the given ID out of the trash, together with any
+// subtasks that were deleted along with it, and returns the IDs of every task
+// that was restored.
+func (tm *TaskManager) Restore(id int) ([]int, error) {
+	var restored []int
+
+	err := tm.mutateIdempotent("restore", func(tasks []Task) ([]Task, error) {
+		i := indexOfAny(tasks, id)
+		if i < 0 {
+			return nil, fmt.Errorf("task with ID %d not found", id)
+		}
+		if !tasks[i].IsDeleted() {
+			return nil, fmt.Errorf("task %d is not in the trash", id)
+		}
+		if p := indexOfAny(tasks, tasks[i].ParentID); p >= 0 && tasks[p].IsDeleted() {
+			return nil, fmt.Errorf("parent task %d is in the trash; restore it first", tasks[p].ID)
+		}
+
+		deletedAt := *tasks[i].DeletedAt
+		restored = append([]int{id}, trashedWith(tasks, id, deletedAt)...)
+
+		now := time.Now()
+		for _, r := range restored {
+			j := indexOfAny(tasks, r)
+			// Timers left running by older versions end when the task was deleted
+			tasks[j].stopTimer(*tasks[j].DeletedAt)
+			tasks[j].DeletedAt = nil
+			tasks[j].UpdatedAt = now
+		}
+
+		return tasks, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// Purge permanently removes the tasks that were moved to the trash before the
+// given time and returns their IDs. Dependencies on purged tasks are dropped.
+// Repositories that are an IDKeeper keep the purged IDs from being reused.
+func (tm *TaskManager) Purge(before time.Time) ([]int, error) {
+	var purged []int
+
+	err := tm.mutateIdempotent("purge", func(tasks []Task) ([]Task, error) {
+		purged = nil
+		for _, t := range tasks {
+			if t.IsDeleted() && t.DeletedAt.Before(before) {
+				purged = append(purged, t.ID)
+			}
+		}
+		if len(purged) == 0 {
+			return tasks, nil
+		}
+
+		kept := tasks[:0]
+		for _, t := range tasks {
+			if !containsID(purged, t.ID) {
+				// Drop dependencies on tasks that no longer exist
+				t.DependsOn = removeIDs(t.DependsOn, purged)
+				kept = append(kept, t)
+			}
+		}
+
+		return kept, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return purged, nil
+}
+
+// loadLive loads every task that is not in the trash.
+func (tm *TaskManager) loadLive() ([]Task, error) {
+	tasks, _, err := tm.repo.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	live := make([]Task, 0, len(tasks))
+	for _, t := range tasks {
+		if !t.IsDeleted() {
+			live = append(live, t)
+		}
+	}
+	return live, nil
+}
+
+// trashedWith returns the IDs of the trashed subtasks below id that were
+// deleted at the same moment, i.e. by the same cascading delete.
+func trashedWith(tasks []Task, id int, deletedAt time.Time) []int {
+	var ids []int
+	for queue := []int{id}; len(queue) > 0; queue = queue[1:] {
+		for _, t := range tasks {
+			if t.ParentID == queue[0] && t.IsDeleted() && t.DeletedAt.Equal(deletedAt) {
+				ids = append(ids, t.ID)
+				queue = append(queue, t.ID)
+			}
+		}
+	}
+	return ids
+}
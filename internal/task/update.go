@@ -0,0 +1,113 @@
+package task
+
+import (
+	"fmt"
+	"time"
+)
+
+// TaskUpdate describes a partial change to a task. Nil fields are left untouched.
+type TaskUpdate struct {
+	Description *string
+	Priority    *Priority
+	DueAt       *time.Time
+	// ClearDue removes the due date; it takes precedence over DueAt.
+	ClearDue bool
+	Project  *string
+	// Tags replaces the whole tag set when non-nil.
+	Tags     *[]string
+	Estimate *Estimate
+	// ClearEstimate removes the estimate; it takes precedence over Estimate.
+	ClearEstimate bool
+}
+
+// IsEmpty reports whether the update would not change anything.
+func (u TaskUpdate) IsEmpty() bool {
+	return u.Description == nil && u.Priority == nil && u.DueAt == nil && !u.ClearDue &&
+		u.Project == nil && u.Tags == nil && u.Estimate == nil && !u.ClearEstimate
+}
+
+// Get returns the task with the given ID.
+func (tm *TaskManager) Get(id int) (Task, error) {
+	tasks, err := tm.loadLive()
+	if err != nil {
+		return Task{}, err
+	}
+
+	i := indexOf(tasks, id)
+	if i < 0 {
+		return Task{}, fmt.Errorf("task with ID %d not found", id)
+	}
+	return tasks[i], nil
+}
+
+// Update applies a partial update to the task with the given ID, keeping its ID
+// and creation time. Like Add, +tag tokens in a new description become tags.
+func (tm *TaskManager) Update(id int, u TaskUpdate) error {
+	if u.IsEmpty() {
+		return fmt.Errorf("nothing to update")
+	}
+
+	var description string
+	var descTags []string
+	if u.Description != nil {
+		description, descTags = ExtractTags(*u.Description)
+		if description == "" {
+			return fmt.Errorf("description cannot be empty")
+		}
+	}
+
+	var project string
+	if u.Project != nil {
+		var err error
+		if project, err = NormalizeProject(*u.Project); err != nil {
+			return err
+		}
+	}
+
+	var tags []string
+	if u.Tags != nil {
+		var err error
+		if tags, err = normalizeTags(*u.Tags); err != nil {
+			return err
+		}
+	}
+
+	return tm.mutateIdempotent("edit", func(tasks []Task) ([]Task, error) {
+		i := indexOf(tasks, id)
+		if i < 0 {
+			return nil, fmt.Errorf("task with ID %d not found", id)
+		}
+
+		t := &tasks[i]
+		if u.Tags != nil {
+			t.Tags = tags
+		}
+		if u.Description != nil {
+			t.Description = description
+			for _, tag := range descTags {
+				t.Tags = addTag(t.Tags, tag)
+			}
+		}
+		if u.Priority != nil {
+			t.Priority = *u.Priority
+		}
+		if u.ClearDue {
+			t.DueAt = nil
+		} else if u.DueAt != nil {
+			due := *u.DueAt
+			t.DueAt = &due
+		}
+		if u.Project != nil {
+			t.Project = project
+		}
+		if u.ClearEstimate {
+			t.Estimate = nil
+		} else if u.Estimate != nil {
+			estimate := *u.Estimate
+			t.Estimate = &estimate
+		}
+		t.UpdatedAt = time.Now()
+
+		return tasks, nil
+	})
+}
@@ -1,12 +1,22 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/amit9838/taskmanager/internal/config"
+	"github.com/amit9838/taskmanager/internal/storage"
 	"github.com/amit9838/taskmanager/internal/task"
+	"github.com/amit9838/taskmanager/pkg/dateparse"
 	"github.com/amit9838/taskmanager/pkg/display"
+	"github.com/amit9838/taskmanager/pkg/query"
 )
 
 type Command interface {
@@ -14,15 +24,50 @@ type Command interface {
 }
 
 // AddCommand
-type AddCommand struct{}
+type AddCommand struct {
+	Priority string
+	Due      string
+	Project  string
+	ParentID int
+	Recur    string
+	Estimate string
+}
 
 func (c *AddCommand) Execute(manager *task.TaskManager, args []string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("please provide a description")
 	}
 
+	priority, err := task.ParsePriority(c.Priority)
+	if err != nil {
+		return err
+	}
+
+	opts := task.AddOptions{Priority: priority, Project: c.Project, ParentID: c.ParentID}
+	if c.Due != "" {
+		due, err := dateparse.Parse(c.Due, time.Now())
+		if err != nil {
+			return err
+		}
+		opts.DueAt = &due
+	}
+	if c.Recur != "" {
+		recur, err := task.ParseRecurrence(c.Recur)
+		if err != nil {
+			return err
+		}
+		opts.Recur = recur
+	}
+	if c.Estimate != "" {
+		estimate, err := task.ParseEstimate(c.Estimate)
+		if err != nil {
+			return err
+		}
+		opts.Estimate = estimate
+	}
+
 	desc := strings.Join(args, " ")
-	id, err := manager.Add(desc)
+	id, err := manager.AddWithOptions(desc, opts)
 	if err != nil {
 		return err
 	}
@@ -32,25 +77,82 @@ func (c *AddCommand) Execute(manager *task.TaskManager, args []string) error {
 }
 
 // ListCommand
-type ListCommand struct{}
+type ListCommand struct {
+	Sort      string
+	Overdue   bool
+	DueBefore string
+	Project   string
+	Tree      bool
+	Archived  bool
+}
 
 func (c *ListCommand) Execute(manager *task.TaskManager, args []string) error {
-	tasks, err := manager.List()
+	project, err := task.NormalizeProject(c.Project)
 	if err != nil {
 		return err
 	}
 
+	filter := task.Filter{
+		Overdue: c.Overdue,
+		Project: project,
+		Now:     time.Now(),
+	}
+	if len(args) > 0 {
+		q, err := parseQuery(args, filter.Now)
+		if err != nil {
+			return err
+		}
+		filter.Where = q
+		// Lets storage with a tag index skip tasks the query cannot match
+		filter.IncludeTags, filter.ExcludeTags = q.Tags()
+	}
+	if c.DueBefore != "" {
+		before, err := dateparse.Parse(c.DueBefore, filter.Now)
+		if err != nil {
+			return err
+		}
+		filter.DueBefore = &before
+	}
+
+	var tasks []task.Task
+	if c.Archived {
+		archived, err := manager.Archived()
+		if err != nil {
+			return err
+		}
+		tasks = task.FilterTasks(archived, filter)
+	} else if tasks, err = manager.Find(filter); err != nil {
+		return err
+	}
+
 	if len(tasks) == 0 {
 		fmt.Println("No tasks found.")
 		return nil
 	}
 
+	switch c.Sort {
+	case "", "priority":
+		task.SortByPriority(tasks)
+	case "id":
+		task.SortByID(tasks)
+	default:
+		return fmt.Errorf("invalid sort order %q (use priority or id)", c.Sort)
+	}
+
+	if c.Tree {
+		display.PrintTaskTree(tasks)
+		return nil
+	}
+
 	display.PrintTasks(tasks)
 	return nil
 }
 
 // DoneCommand
-type DoneCommand struct{}
+type DoneCommand struct {
+	CompleteParents bool
+	Force           bool
+}
 
 func (c *DoneCommand) Execute(manager *task.TaskManager, args []string) error {
 	if len(args) == 0 {
@@ -62,16 +164,396 @@ func (c *DoneCommand) Execute(manager *task.TaskManager, args []string) error {
 		return fmt.Errorf("invalid task ID: %v", err)
 	}
 
-	if err := manager.MarkDone(id); err != nil {
+	result, err := manager.MarkDoneWithOptions(id, task.DoneOptions{CompleteParents: c.CompleteParents, Force: c.Force})
+	if err != nil {
 		return err
 	}
 
 	fmt.Printf("Task %d marked as done.\n", id)
+	for _, parentID := range result.AutoCompleted {
+		fmt.Printf("Task %d marked as done (all subtasks closed).\n", parentID)
+	}
+	if result.NextID != 0 {
+		fmt.Printf("Next occurrence scheduled as task %d.\n", result.NextID)
+	}
+	return nil
+}
+
+// StatusCommand moves a task to a fixed status, e.g. `start` or `cancel`.
+type StatusCommand struct {
+	Status task.TaskStatus
+	Verb   string
+}
+
+func (c *StatusCommand) Execute(manager *task.TaskManager, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("please provide a task ID")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid task ID: %v", err)
+	}
+
+	if err := manager.SetStatus(id, c.Status); err != nil {
+		return err
+	}
+
+	fmt.Printf("Task %d %s.\n", id, c.Verb)
+	return nil
+}
+
+// EditCommand changes an existing task. Without flags it opens the task in $EDITOR.
+// A value of "none" clears the due date or project.
+type EditCommand struct {
+	Description string
+	Priority    string
+	Due         string
+	Project     string
+	Estimate    string
+}
+
+func (c *EditCommand) Execute(manager *task.TaskManager, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("please provide a task ID")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid task ID: %v", err)
+	}
+
+	var update task.TaskUpdate
+	if c.Description == "" && c.Priority == "" && c.Due == "" && c.Project == "" && c.Estimate == "" {
+		t, err := manager.Get(id)
+		if err != nil {
+			return err
+		}
+		if update, err = editInEditor(t); err != nil {
+			return err
+		}
+		if update.IsEmpty() {
+			fmt.Println("No changes.")
+			return nil
+		}
+	} else {
+		if update, err = c.flagUpdate(); err != nil {
+			return err
+		}
+	}
+
+	if err := manager.Update(id, update); err != nil {
+		return err
+	}
+
+	fmt.Printf("Task %d updated.\n", id)
+	return nil
+}
+
+func (c *EditCommand) flagUpdate() (task.TaskUpdate, error) {
+	var u task.TaskUpdate
+
+	if c.Description != "" {
+		u.Description = &c.Description
+	}
+
+	if c.Priority != "" {
+		priority, err := task.ParsePriority(c.Priority)
+		if err != nil {
+			return u, err
+		}
+		u.Priority = &priority
+	}
+
+	switch c.Due {
+	case "":
+	case "none":
+		u.ClearDue = true
+	default:
+		due, err := dateparse.Parse(c.Due, time.Now())
+		if err != nil {
+			return u, err
+		}
+		u.DueAt = &due
+	}
+
+	switch c.Project {
+	case "":
+	case "none":
+		empty := ""
+		u.Project = &empty
+	default:
+		u.Project = &c.Project
+	}
+
+	switch c.Estimate {
+	case "":
+	case "none":
+		u.ClearEstimate = true
+	default:
+		estimate, err := task.ParseEstimate(c.Estimate)
+		if err != nil {
+			return u, err
+		}
+		u.Estimate = estimate
+	}
+
+	return u, nil
+}
+
+// PriorityCommand
+type PriorityCommand struct{}
+
+func (c *PriorityCommand) Execute(manager *task.TaskManager, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("please provide a task ID and a priority level")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid task ID: %v", err)
+	}
+
+	priority, err := task.ParsePriority(args[1])
+	if err != nil {
+		return err
+	}
+
+	if err := manager.SetPriority(id, priority); err != nil {
+		return err
+	}
+
+	fmt.Printf("Task %d priority set to %s.\n", id, priority)
+	return nil
+}
+
+// TagCommand
+type TagCommand struct{}
+
+func (c *TagCommand) Execute(manager *task.TaskManager, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("please provide a task ID and at least one +tag or -tag")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid task ID: %v", err)
+	}
+
+	add, remove, err := parseTagArgs(args[1:])
+	if err != nil {
+		return err
+	}
+
+	if err := manager.Tag(id, add, remove); err != nil {
+		return err
+	}
+
+	fmt.Printf("Task %d tags updated.\n", id)
+	return nil
+}
+
+// TagsCommand
+type TagsCommand struct{}
+
+func (c *TagsCommand) Execute(manager *task.TaskManager, args []string) error {
+	counts, err := manager.TagCounts()
+	if err != nil {
+		return err
+	}
+
+	if len(counts) == 0 {
+		fmt.Println("No tags found.")
+		return nil
+	}
+
+	display.PrintTagCounts(counts)
+	return nil
+}
+
+// ParentCommand
+type ParentCommand struct{}
+
+func (c *ParentCommand) Execute(manager *task.TaskManager, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("please provide a task ID and a parent task ID (or \"none\")")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid task ID: %v", err)
+	}
+
+	parentID := 0
+	if args[1] != "none" {
+		parentID, err = strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid parent task ID: %v", err)
+		}
+	}
+
+	if err := manager.SetParent(id, parentID); err != nil {
+		return err
+	}
+
+	if parentID == 0 {
+		fmt.Printf("Task %d is now a top-level task.\n", id)
+		return nil
+	}
+	fmt.Printf("Task %d is now a subtask of %d.\n", id, parentID)
+	return nil
+}
+
+// DependCommand
+type DependCommand struct {
+	Remove bool
+}
+
+func (c *DependCommand) Execute(manager *task.TaskManager, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("please provide a task ID and the IDs it depends on")
+	}
+
+	ids := make([]int, len(args))
+	for i, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid task ID: %v", err)
+		}
+		ids[i] = id
+	}
+
+	if c.Remove {
+		if err := manager.RemoveDependencies(ids[0], ids[1:]...); err != nil {
+			return err
+		}
+		fmt.Printf("Task %d dependencies removed.\n", ids[0])
+		return nil
+	}
+
+	if err := manager.AddDependencies(ids[0], ids[1:]...); err != nil {
+		return err
+	}
+
+	fmt.Printf("Task %d now depends on %s.\n", ids[0], strings.Join(args[1:], ", "))
+	return nil
+}
+
+// NextCommand
+type NextCommand struct {
+	All bool
+}
+
+func (c *NextCommand) Execute(manager *task.TaskManager, args []string) error {
+	if c.All {
+		plan, err := manager.Plan()
+		if err != nil {
+			return err
+		}
+		if len(plan) == 0 {
+			fmt.Println("No open tasks.")
+			return nil
+		}
+		display.PrintPlan(plan)
+		return nil
+	}
+
+	tasks, err := manager.Next()
+	if err != nil {
+		return err
+	}
+
+	if len(tasks) == 0 {
+		fmt.Println("Nothing is actionable right now.")
+		return nil
+	}
+
+	display.PrintTasks(tasks)
+	return nil
+}
+
+// RecurCommand manages recurring series: `recur list` and `recur stop <id>`.
+type RecurCommand struct{}
+
+func (c *RecurCommand) Execute(manager *task.TaskManager, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("please provide a subcommand: list or stop <id>")
+	}
+
+	switch args[0] {
+	case "list":
+		tasks, err := manager.Recurring()
+		if err != nil {
+			return err
+		}
+		if len(tasks) == 0 {
+			fmt.Println("No recurring tasks.")
+			return nil
+		}
+		display.PrintRecurring(tasks)
+		return nil
+
+	case "stop":
+		if len(args) < 2 {
+			return fmt.Errorf("please provide a task ID")
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid task ID: %v", err)
+		}
+		if err := manager.StopRecurrence(id); err != nil {
+			return err
+		}
+		fmt.Printf("Recurrence of task %d stopped.\n", id)
+		return nil
+	}
+
+	return fmt.Errorf("unknown recur subcommand: %s (use list or stop)", args[0])
+}
+
+// MoveCommand
+type MoveCommand struct{}
+
+func (c *MoveCommand) Execute(manager *task.TaskManager, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("please provide a task ID and a project")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid task ID: %v", err)
+	}
+
+	if err := manager.Move(id, args[1]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Task %d moved to %s.\n", id, args[1])
+	return nil
+}
+
+// ProjectsCommand
+type ProjectsCommand struct{}
+
+func (c *ProjectsCommand) Execute(manager *task.TaskManager, args []string) error {
+	projects, err := manager.Projects()
+	if err != nil {
+		return err
+	}
+
+	if len(projects) == 0 {
+		fmt.Println("No projects found.")
+		return nil
+	}
+
+	display.PrintProjectTree(projects)
 	return nil
 }
 
 // DeleteCommand
-type DeleteCommand struct{}
+type DeleteCommand struct {
+	Cascade bool
+}
 
 func (c *DeleteCommand) Execute(manager *task.TaskManager, args []string) error {
 	if len(args) == 0 {
@@ -83,35 +565,568 @@ func (c *DeleteCommand) Execute(manager *task.TaskManager, args []string) error
 		return fmt.Errorf("invalid task ID: %v", err)
 	}
 
-	if err := manager.Delete(id); err != nil {
+	removed, err := manager.DeleteWithOptions(id, task.DeleteOptions{Cascade: c.Cascade})
+	if err != nil {
+		return err
+	}
+
+	if len(removed) > 1 {
+		fmt.Printf("Task %d and %d subtask(s) moved to the trash.\n", id, len(removed)-1)
+		return nil
+	}
+	fmt.Printf("Task %d moved to the trash.\n", id)
+	return nil
+}
+
+// ArchiveCommand moves completed tasks to the archive, optionally only those
+// completed before DoneBefore.
+type ArchiveCommand struct {
+	DoneBefore string
+}
+
+func (c *ArchiveCommand) Execute(manager *task.TaskManager, args []string) error {
+	var before time.Time
+	if c.DoneBefore != "" {
+		var err error
+		if before, err = dateparse.Parse(c.DoneBefore, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	archived, err := manager.Archive(before)
+	if err != nil {
+		return err
+	}
+
+	if len(archived) == 0 {
+		fmt.Println("No completed tasks to archive.")
+		return nil
+	}
+	fmt.Printf("Archived %d completed task(s).\n", len(archived))
+	return nil
+}
+
+// TrackCommand starts and stops the time tracking timer ("start <id>", "stop")
+// and shows what is being tracked ("status").
+type TrackCommand struct{}
+
+func (c *TrackCommand) Execute(manager *task.TaskManager, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("please choose start <id>, stop or status")
+	}
+
+	now := time.Now()
+	switch args[0] {
+	case "start":
+		if len(args) < 2 {
+			return fmt.Errorf("please provide a task ID")
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid task ID: %v", err)
+		}
+
+		stopped, err := manager.StartTimer(id)
+		if err != nil {
+			return err
+		}
+		if stopped != 0 {
+			fmt.Printf("Stopped tracking task %d.\n", stopped)
+		}
+		fmt.Printf("Started tracking task %d.\n", id)
+
+	case "stop":
+		t, tracked, err := manager.StopTimer()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Stopped tracking task %d after %s (%s in total).\n",
+			t.ID, display.FormatDuration(tracked.Duration(now)), display.FormatDuration(t.TrackedTime(now)))
+
+	case "status":
+		t, err := manager.RunningTimer()
+		if err != nil {
+			return err
+		}
+		if t == nil {
+			fmt.Println("No timer is running.")
+			return nil
+		}
+		running, _ := t.Running()
+		fmt.Printf("Tracking task %d: %s\n", t.ID, t.Description)
+		fmt.Printf("Running for %s since %s (%s in total).\n",
+			display.FormatDuration(running.Duration(now)), running.Start.Local().Format("15:04"), display.FormatDuration(t.TrackedTime(now)))
+
+	default:
+		return fmt.Errorf("unknown track command %q (use start, stop or status)", args[0])
+	}
+	return nil
+}
+
+// PomodoroCommand runs pomodoro sessions on a task: Cycles work periods of
+// Work, separated by breaks of Break. Each work period is logged as tracked
+// time on the task; one stopped with Ctrl-C is logged as interrupted.
+type PomodoroCommand struct {
+	Work   time.Duration
+	Break  time.Duration
+	Cycles int
+}
+
+func (c *PomodoroCommand) Execute(manager *task.TaskManager, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("please provide a task ID")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid task ID: %v", err)
+	}
+	if c.Work <= 0 || c.Break < 0 || c.Cycles < 1 {
+		return fmt.Errorf("work time and cycles must be positive, and breaks cannot be negative")
+	}
+
+	t, err := manager.Get(id)
+	if err != nil {
+		return err
+	}
+	stopped, err := manager.StartPomodoro(id)
+	if err != nil {
+		return err
+	}
+	if stopped != 0 {
+		fmt.Printf("Stopped tracking task %d.\n", stopped)
+	}
+
+	// Catch Ctrl-C so an interrupted session is still recorded
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Pomodoro on task %d: %s (Ctrl-C to stop)\n", id, t.Description)
+	for cycle := 1; cycle <= c.Cycles; cycle++ {
+		start := time.Now()
+		finished := countdown(ctx, fmt.Sprintf("Work %d/%d", cycle, c.Cycles), c.Work)
+		end := time.Now()
+
+		if !finished {
+			if end.Sub(start) < time.Second {
+				fmt.Println("Stopped; nothing recorded.")
+				return nil
+			}
+			if err := manager.LogPomodoro(id, start, end, true); err != nil {
+				return err
+			}
+			fmt.Printf("Stopped after %s; recorded as an interrupted pomodoro.\n", display.FormatDuration(end.Sub(start)))
+			return nil
+		}
+
+		if err := manager.LogPomodoro(id, start, end, false); err != nil {
+			return err
+		}
+		fmt.Printf("\aPomodoro %d/%d done.\n", cycle, c.Cycles)
+
+		if cycle == c.Cycles || c.Break == 0 {
+			continue
+		}
+		if !countdown(ctx, "Break", c.Break) {
+			fmt.Printf("Stopped during the break after %d pomodoro(s).\n", cycle)
+			return nil
+		}
+		fmt.Println("\aBreak over.")
+	}
+
+	fmt.Printf("Completed %d pomodoro(s) on task %d.\n", c.Cycles, id)
+	return nil
+}
+
+// ShowCommand prints all details of a single task.
+type ShowCommand struct{}
+
+func (c *ShowCommand) Execute(manager *task.TaskManager, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("please provide a task ID")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid task ID: %v", err)
+	}
+
+	t, err := manager.Get(id)
+	if err != nil {
+		return err
+	}
+
+	display.PrintTask(t)
+	return nil
+}
+
+// TimesheetCommand prints the time tracked during the current week, or
+// between From and To.
+type TimesheetCommand struct {
+	Week bool
+	From string
+	To   string
+}
+
+func (c *TimesheetCommand) Execute(manager *task.TaskManager, args []string) error {
+	now := time.Now()
+
+	// Default to the current week, Monday to Sunday
+	today := dateparse.StartOfDay(now)
+	from := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	to := from.AddDate(0, 0, 7)
+
+	if !c.Week && (c.From != "" || c.To != "") {
+		if c.From != "" {
+			parsed, err := dateparse.Parse(c.From, now)
+			if err != nil {
+				return err
+			}
+			from = dateparse.StartOfDay(parsed)
+		}
+		to = today.AddDate(0, 0, 1)
+		if c.To != "" {
+			parsed, err := dateparse.Parse(c.To, now)
+			if err != nil {
+				return err
+			}
+			// Include the whole of the last day
+			to = dateparse.StartOfDay(parsed).AddDate(0, 0, 1)
+		}
+	}
+
+	entries, err := manager.Timesheet(from, to)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("No time tracked from %s to %s.\n", from.Format("2006-01-02"), to.AddDate(0, 0, -1).Format("2006-01-02"))
+		return nil
+	}
+
+	display.PrintTimesheet(entries, from, to)
+	return nil
+}
+
+// StatsCommand prints reports about completed work. The only report so far is
+// "cycle-time".
+type StatsCommand struct {
+	By   string
+	From string
+	To   string
+}
+
+func (c *StatsCommand) Execute(manager *task.TaskManager, args []string) error {
+	if len(args) == 0 || args[0] != "cycle-time" {
+		return fmt.Errorf("please choose a report: tm stats cycle-time")
+	}
+
+	now := time.Now()
+	opts := task.CycleTimeOptions{By: c.By}
+	if c.From != "" {
+		from, err := dateparse.Parse(c.From, now)
+		if err != nil {
+			return err
+		}
+		opts.From = dateparse.StartOfDay(from)
+	}
+	if c.To != "" {
+		to, err := dateparse.Parse(c.To, now)
+		if err != nil {
+			return err
+		}
+		// Include the whole of the last day
+		opts.To = dateparse.StartOfDay(to).AddDate(0, 0, 1)
+	}
+
+	stats, err := manager.CycleTimes(opts)
+	if err != nil {
+		return err
+	}
+
+	if len(stats) == 0 {
+		fmt.Println("No completed tasks in this period.")
+		return nil
+	}
+
+	display.PrintCycleTimes(stats, c.By)
+	return nil
+}
+
+// ReportCommand compares estimated with actual effort. Threshold is the
+// overrun, in percent, above which a task is flagged.
+type ReportCommand struct {
+	Against   string
+	Threshold float64
+}
+
+func (c *ReportCommand) Execute(manager *task.TaskManager, args []string) error {
+	if len(args) == 0 || args[0] != "estimates" {
+		return fmt.Errorf("please choose a report: tm report estimates")
+	}
+
+	report, err := manager.Estimates(task.EstimateOptions{Against: c.Against, Threshold: c.Threshold / 100})
+	if err != nil {
+		return err
+	}
+
+	if len(report.Projects) == 0 {
+		fmt.Println("No completed tasks with an estimate and recorded effort.")
+		return nil
+	}
+
+	display.PrintEstimateReport(report, c.Threshold)
+	return nil
+}
+
+// MigrateStorageCommand copies the tasks from the current storage backend into
+// a new file for the backend named by To ("json", "db" or "log").
+type MigrateStorageCommand struct {
+	To string
+}
+
+func (c *MigrateStorageCommand) Execute(manager *task.TaskManager, args []string) error {
+	// Every backend keeps its file next to where tasks.json would be
+	var current, tasksPath string
+	switch src := manager.Repository().(type) {
+	case *storage.JSONStorage:
+		current, tasksPath = src.Path(), src.Path()
+	case *storage.DBStorage:
+		current, tasksPath = src.Path(), storage.JSONPath(src.Path())
+	case *storage.EventLogStorage:
+		current, tasksPath = src.Path(), storage.JSONPath(src.Path())
+	default:
+		return fmt.Errorf("the current storage backend cannot be migrated")
+	}
+
+	var dst task.Repository
+	var path string
+
+	switch c.To {
+	case config.StorageJSON:
+		path = tasksPath
+		dst = storage.NewJSONStorage(path)
+	case config.StorageDB:
+		path = storage.DBPath(tasksPath)
+		dst = storage.NewDBStorage(path)
+	case config.StorageLog:
+		path = storage.EventLogPath(tasksPath)
+		dst = storage.NewEventLogStorage(path)
+	default:
+		return fmt.Errorf("please choose a storage backend with --to (%s, %s or %s)", config.StorageJSON, config.StorageDB, config.StorageLog)
+	}
+
+	if path == current {
+		return fmt.Errorf("tasks are already stored in %s", path)
+	}
+
+	n, err := manager.CopyTo(dst)
+	if err != nil {
+		return fmt.Errorf("cannot migrate to %s: %w", path, err)
+	}
+
+	fmt.Printf("Copied %d task(s) to %s.\n", n, path)
+	if configPath, err := config.ResolvePath(); err == nil {
+		fmt.Printf("Set \"storage: %s\" in %s to use it.\n", c.To, configPath)
+	}
+	return nil
+}
+
+// TrashCommand
+type TrashCommand struct{}
+
+func (c *TrashCommand) Execute(manager *task.TaskManager, args []string) error {
+	tasks, err := manager.Trash()
+	if err != nil {
+		return err
+	}
+
+	if len(tasks) == 0 {
+		fmt.Println("The trash is empty.")
+		return nil
+	}
+
+	display.PrintTrash(tasks)
+	return nil
+}
+
+// RestoreCommand
+type RestoreCommand struct{}
+
+func (c *RestoreCommand) Execute(manager *task.TaskManager, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("please provide a task ID")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid task ID: %v", err)
+	}
+
+	restored, err := manager.Restore(id)
+	if err != nil {
+		return err
+	}
+
+	if len(restored) > 1 {
+		fmt.Printf("Task %d and %d subtask(s) restored.\n", id, len(restored)-1)
+		return nil
+	}
+	fmt.Printf("Task %d restored.\n", id)
+	return nil
+}
+
+// PurgeCommand permanently removes tasks from the trash, optionally only
+// those deleted longer ago than OlderThan (e.g. "30d").
+type PurgeCommand struct {
+	OlderThan string
+}
+
+func (c *PurgeCommand) Execute(manager *task.TaskManager, args []string) error {
+	before := time.Now()
+	if c.OlderThan != "" {
+		var err error
+		if before, err = dateparse.Ago(c.OlderThan, before); err != nil {
+			return err
+		}
+	}
+
+	purged, err := manager.Purge(before)
+	if err != nil {
+		return err
+	}
+
+	if len(purged) == 0 {
+		fmt.Println("Nothing to purge.")
+		return nil
+	}
+	fmt.Printf("Purged %d task(s) from the trash.\n", len(purged))
+	return nil
+}
+
+// UndoCommand reverts the last n operations (default 1). With Redo set it
+// re-applies undone operations instead.
+type UndoCommand struct {
+	Redo bool
+}
+
+func (c *UndoCommand) Execute(manager *task.TaskManager, args []string) error {
+	n := 1
+	if len(args) > 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+			return fmt.Errorf("invalid count: %s", args[0])
+		}
+	}
+
+	replay, verb := manager.Undo, "Undid"
+	if c.Redo {
+		replay, verb = manager.Redo, "Redid"
+	}
+
+	entries, err := replay(n)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		fmt.Printf("%s #%d: %s (tasks %s)\n", verb, e.Seq, e.Op, task.FormatIDs(e.TaskIDs()))
+	}
+	return nil
+}
+
+// HistoryCommand
+type HistoryCommand struct {
+	Limit int
+}
+
+func (c *HistoryCommand) Execute(manager *task.TaskManager, args []string) error {
+	entries, err := manager.History(c.Limit)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No history yet.")
+		return nil
+	}
+
+	display.PrintHistory(entries)
+	return nil
+}
+
+// LogCommand
+type LogCommand struct{}
+
+func (c *LogCommand) Execute(manager *task.TaskManager, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("please provide a task ID")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid task ID: %v", err)
+	}
+
+	log, err := manager.Log(id)
+	if err != nil {
 		return err
 	}
 
-	fmt.Printf("Task %d deleted.\n", id)
+	display.PrintTaskLog(id, log)
 	return nil
 }
 
 // SearchCommand
-type SearchCommand struct{}
+type SearchCommand struct {
+	IncludeArchive bool
+	Regex          bool
+	Fuzzy          bool
+	Word           bool
+	Where          string
+}
 
 func (c *SearchCommand) Execute(manager *task.TaskManager, args []string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("please provide a search term")
 	}
 
-	query := strings.Join(args, " ")
-	tasks, err := manager.Search(query)
+	opts := task.SearchOptions{IncludeArchive: c.IncludeArchive, Mode: task.SearchSubstring}
+	modes := 0
+	for _, m := range []struct {
+		set  bool
+		mode task.SearchMode
+	}{{c.Regex, task.SearchRegex}, {c.Fuzzy, task.SearchFuzzy}, {c.Word, task.SearchWord}} {
+		if m.set {
+			opts.Mode = m.mode
+			modes++
+		}
+	}
+	if modes > 1 {
+		return fmt.Errorf("choose only one of --regex, --fuzzy and --word")
+	}
+
+	if c.Where != "" {
+		q, err := parseQuery([]string{c.Where}, time.Now())
+		if err != nil {
+			return err
+		}
+		opts.Where = q
+	}
+
+	results, err := manager.SearchWithOptions(strings.Join(args, " "), opts)
 	if err != nil {
 		return err
 	}
 
-	if len(tasks) == 0 {
+	if len(results) == 0 {
 		fmt.Println("No results found.")
 		return nil
 	}
 
-	fmt.Printf("Found %d results:\n", len(tasks))
-	display.PrintTasks(tasks)
+	fmt.Printf("Found %d results:\n", len(results))
+	display.PrintSearchResults(results)
 	return nil
 }
 
@@ -123,14 +1138,135 @@ func (c *HelpCommand) Execute(manager *task.TaskManager, args []string) error {
 	return nil
 }
 
+// parseQuery parses the arguments as a single query, pointing at the problem
+// in the query when it is invalid.
+func parseQuery(args []string, now time.Time) (*query.Query, error) {
+	q, err := query.Parse(strings.Join(args, " "), now)
+	var qerr *query.Error
+	if errors.As(err, &qerr) {
+		return nil, fmt.Errorf("invalid query at %v\n  %s", qerr, strings.ReplaceAll(qerr.Context(), "\n", "\n  "))
+	}
+	return q, err
+}
+
+// parseTagArgs splits "+tag" and "-tag" arguments into tags to include and exclude.
+func parseTagArgs(args []string) (include, exclude []string, err error) {
+	for _, arg := range args {
+		var list *[]string
+		switch {
+		case strings.HasPrefix(arg, "+"):
+			list = &include
+		case strings.HasPrefix(arg, "-"):
+			list = &exclude
+		default:
+			return nil, nil, fmt.Errorf("unexpected argument %q (tags must start with + or -)", arg)
+		}
+
+		tag, err := task.NormalizeTag(arg[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		*list = append(*list, tag)
+	}
+	return include, exclude, nil
+}
+
 func printUsage() {
 	fmt.Println("Task Manager CLI")
 	fmt.Println("\nUsage:")
-	fmt.Println("  add \"<description>\"   Create a new task")
-	fmt.Println("  list                  List all tasks")
+	fmt.Println("  tm [--file <path>] [--lock-timeout <d>] <command> [arguments]")
+	fmt.Println("\nCommands:")
+	fmt.Println("  add \"<description>\"   Create a new task (+tag words become tags)")
+	fmt.Println("      --priority <lvl>  Set the priority (none, low, medium, high, urgent)")
+	fmt.Println("      --due <date>      Set the due date (tomorrow, fri, next fri, 2026-11-01, +3d)")
+	fmt.Println("      --project <name>  Put the task in a project (dotted, e.g. work.api)")
+	fmt.Println("      --parent <id>     Create the task as a subtask of another task")
+	fmt.Println("      --recur <rule>    Repeat the task (daily, weekly:fri, monthly:15, \"every 2 weeks\")")
+	fmt.Println("      --estimate <e>    Expected effort as a duration (3h, 90m) or story points (5pt)")
+	fmt.Println("  list [query]          List tasks, most urgent first, optionally filtered by a query")
+	fmt.Println("                        e.g. 'status:open and (+backend or priority>=high) and due<+7d'")
+	fmt.Println("      --sort <order>    Sort by priority (default) or id")
+	fmt.Println("      --overdue         Only show open tasks past their due date")
+	fmt.Println("      --due-before <d>  Only show tasks due before the given date")
+	fmt.Println("      --project <name>  Only show tasks in a project and its sub-projects")
+	fmt.Println("      --tree            Show subtasks indented under their parents")
+	fmt.Println("      --archived        List archived tasks instead")
 	fmt.Println("  done <id>             Mark a task as completed")
-	fmt.Println("  del <id>              Delete a task")
-	fmt.Println("  search \"<term>\"       Search tasks")
+	fmt.Println("      --complete-parent Also complete parents whose subtasks are all closed")
+	fmt.Println("      --force           Complete even if prerequisites are still open")
+	fmt.Println("  start <id>            Mark a task as in progress")
+	fmt.Println("  block <id>            Mark a task as blocked")
+	fmt.Println("  wait <id>             Mark a task as waiting on someone else")
+	fmt.Println("  cancel <id>           Cancel a task")
+	fmt.Println("  reopen <id>           Move a task back to pending")
+	fmt.Println("  edit <id>             Edit a task in $EDITOR, or change fields with flags:")
+	fmt.Println("      --desc <text>     New description")
+	fmt.Println("      --priority <lvl>  New priority")
+	fmt.Println("      --due <date>      New due date (\"none\" clears it)")
+	fmt.Println("      --project <name>  New project (\"none\" clears it)")
+	fmt.Println("      --estimate <e>    New estimate (\"none\" clears it)")
+	fmt.Println("  priority <id> <lvl>   Change the priority of a task")
+	fmt.Println("  tag <id> +a -b        Add and remove tags on a task")
+	fmt.Println("  tags                  List all tags with open/done counts")
+	fmt.Println("  depend <id> <on>...   Make a task depend on other tasks")
+	fmt.Println("      --remove          Remove the given dependencies instead")
+	fmt.Println("  next                  Show actionable tasks, most urgent first")
+	fmt.Println("      --all             Show every open task in dependency order")
+	fmt.Println("  recur list            List active recurring series")
+	fmt.Println("  recur stop <id>       Stop the series a task belongs to")
+	fmt.Println("  move <id> <project>   Move a task to another project")
+	fmt.Println("  projects              Show the project tree with progress")
+	fmt.Println("  del <id>              Move a task to the trash")
+	fmt.Println("      --cascade         Also delete all of its subtasks")
+	fmt.Println("  parent <id> <parent>  Make a task a subtask of another (\"none\" to detach)")
+	fmt.Println("  trash                 List deleted tasks")
+	fmt.Println("  restore <id>          Restore a task (and subtasks deleted with it) from the trash")
+	fmt.Println("  purge                 Permanently remove tasks from the trash")
+	fmt.Println("      --older-than <n>  Only purge tasks deleted more than n ago (e.g. 30d, 2w)")
+	fmt.Println("  search \"<term>\"       Search task descriptions, best matches first")
+	fmt.Println("      --word            Only match whole words")
+	fmt.Println("      --regex           Treat the term as a regular expression")
+	fmt.Println("      --fuzzy           Match letters in order and tolerate typos")
+	fmt.Println("      --where <query>   Only search tasks matching a query, as for list")
+	fmt.Println("      --include-archive Also search archived tasks")
+	fmt.Println("  archive               Move completed tasks to the archive file")
+	fmt.Println("      --done-before <d> Only archive tasks completed before this date")
+	fmt.Println("  undo [n]              Undo the last n changes (default 1)")
+	fmt.Println("  redo [n]              Redo the last n undone changes (default 1)")
+	fmt.Println("  history               Show recent changes")
+	fmt.Println("      --limit <n>       Number of entries to show (default 20, 0 for all)")
+	fmt.Println("  log <id>              Show every recorded change to a task")
+	fmt.Println("  track start <id>      Start tracking time on a task (stops any running timer)")
+	fmt.Println("  track stop            Stop the running timer")
+	fmt.Println("  track status          Show the running timer")
+	fmt.Println("  pomodoro <id>         Run pomodoro sessions on a task, logging the time on it")
+	fmt.Println("      --work <d>        Length of a work period (default 25m)")
+	fmt.Println("      --break <d>       Length of a break (default 5m)")
+	fmt.Println("      --cycles <n>      Number of work periods (default 4)")
+	fmt.Println("  show <id>             Show all details of a task, including tracked time")
+	fmt.Println("  timesheet             Show tracked time per day, task and project")
+	fmt.Println("      --week            The current week, Monday to Sunday (default)")
+	fmt.Println("      --from <d>        Start of the period")
+	fmt.Println("      --to <d>          End of the period (default today)")
+	fmt.Println("  stats cycle-time      Show lead and cycle time (median, p90) of completed tasks")
+	fmt.Println("      --by <group>      Group by project or tag")
+	fmt.Println("      --from <d>        Only tasks completed on or after this date")
+	fmt.Println("      --to <d>          Only tasks completed on or before this date")
+	fmt.Println("  report estimates      Compare estimates of completed tasks with their actual effort")
+	fmt.Println("      --against <m>     Measure effort as tracked (default) or cycle time")
+	fmt.Println("      --threshold <p>   Flag tasks that overran by more than p percent (default 50)")
+	fmt.Println("  migrate-storage       Copy the tasks into another storage backend")
+	fmt.Println("      --to <backend>    Backend to migrate to (json, db, log)")
 	fmt.Println("  help                  Show this help message")
+	fmt.Println("\nData location (first match wins):")
+	fmt.Println("  --file <path>         Use the given tasks file")
+	fmt.Println("  $TM_FILE              Path to the tasks file")
+	fmt.Println("  default               $XDG_DATA_HOME/tm/tasks.json (~/.local/share/tm/tasks.json)")
+	fmt.Println("\nWaiting for other tm commands (first match wins):")
+	fmt.Println("  --lock-timeout <d>    How long to wait for the tasks file lock, e.g. 10s")
+	fmt.Println("  $TM_LOCK_TIMEOUT      Lock timeout")
+	fmt.Println("  default               5s")
+	fmt.Println("\nConfiguration ($TM_CONFIG, default $XDG_CONFIG_HOME/tm/config.yaml):")
+	fmt.Println("  storage: json|db|log  Keep tasks in tasks.json, the tasks.db database or the tasks.jsonl event log")
 	fmt.Println("")
 }
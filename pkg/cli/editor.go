@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/amit9838/taskmanager/internal/task"
+	"github.com/amit9838/taskmanager/pkg/dateparse"
+)
+
+// editableTask is the subset of a task that can be changed in $EDITOR.
+type editableTask struct {
+	Description string   `json:"description"`
+	Priority    string   `json:"priority"`
+	Due         string   `json:"due"`
+	Project     string   `json:"project"`
+	Tags        []string `json:"tags"`
+	Estimate    string   `json:"estimate"`
+}
+
+func newEditableTask(t task.Task) editableTask {
+	e := editableTask{
+		Description: t.Description,
+		Priority:    t.Priority.String(),
+		Project:     t.Project,
+		Tags:        t.Tags,
+	}
+	if t.DueAt != nil {
+		e.Due = t.DueAt.Format("2006-01-02")
+	}
+	if t.Estimate != nil {
+		e.Estimate = t.Estimate.String()
+	}
+	if e.Tags == nil {
+		e.Tags = []string{}
+	}
+	return e
+}
+
+// editInEditor opens the task as JSON in $VISUAL or $EDITOR and returns the
+// update for the fields that were changed.
+func editInEditor(t task.Task) (task.TaskUpdate, error) {
+	original := newEditableTask(t)
+	data, err := json.MarshalIndent(original, "", "  ")
+	if err != nil {
+		return task.TaskUpdate{}, fmt.Errorf("failed to encode task: %w", err)
+	}
+
+	file, err := os.CreateTemp("", fmt.Sprintf("tm-edit-%d-*.json", t.ID))
+	if err != nil {
+		return task.TaskUpdate{}, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return task.TaskUpdate{}, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return task.TaskUpdate{}, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	if err := runEditor(file.Name()); err != nil {
+		return task.TaskUpdate{}, err
+	}
+
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		return task.TaskUpdate{}, fmt.Errorf("failed to read edited task: %w", err)
+	}
+
+	var changed editableTask
+	if err := json.Unmarshal(edited, &changed); err != nil {
+		return task.TaskUpdate{}, fmt.Errorf("failed to parse edited task: %w", err)
+	}
+
+	return diffEditable(original, changed)
+}
+
+// runEditor opens path in the user's editor, attached to the terminal.
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// The editor may carry arguments, e.g. "code --wait"
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+	return nil
+}
+
+// diffEditable converts the fields that differ between two renderings into an update.
+func diffEditable(original, changed editableTask) (task.TaskUpdate, error) {
+	var u task.TaskUpdate
+
+	if changed.Description != original.Description {
+		u.Description = &changed.Description
+	}
+
+	if changed.Priority != original.Priority {
+		priority, err := task.ParsePriority(changed.Priority)
+		if err != nil {
+			return u, err
+		}
+		u.Priority = &priority
+	}
+
+	if changed.Due != original.Due {
+		if strings.TrimSpace(changed.Due) == "" {
+			u.ClearDue = true
+		} else {
+			due, err := dateparse.Parse(changed.Due, time.Now())
+			if err != nil {
+				return u, err
+			}
+			u.DueAt = &due
+		}
+	}
+
+	if changed.Project != original.Project {
+		u.Project = &changed.Project
+	}
+
+	if changed.Estimate != original.Estimate {
+		if strings.TrimSpace(changed.Estimate) == "" {
+			u.ClearEstimate = true
+		} else {
+			estimate, err := task.ParseEstimate(changed.Estimate)
+			if err != nil {
+				return u, err
+			}
+			u.Estimate = estimate
+		}
+	}
+
+	if changed.Tags == nil {
+		changed.Tags = []string{}
+	}
+	if !reflect.DeepEqual(changed.Tags, original.Tags) {
+		u.Tags = &changed.Tags
+	}
+
+	return u, nil
+}
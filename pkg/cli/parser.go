@@ -3,6 +3,8 @@ package cli
 import (
 	"flag"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/amit9838/taskmanager/internal/task"
 )
@@ -20,36 +22,244 @@ func ExecuteCommand(manager *task.TaskManager, args []string) error {
 
 	switch command {
 	case "add":
-		cmd = &AddCommand{}
+		addCmd := &AddCommand{}
+		cmd = addCmd
 		fs := flag.NewFlagSet("add", flag.ContinueOnError)
-		if err := fs.Parse(remainingArgs); err != nil {
+		fs.StringVar(&addCmd.Priority, "priority", "", "task priority (none, low, medium, high, urgent)")
+		fs.StringVar(&addCmd.Due, "due", "", "due date (tomorrow, next fri, 2026-11-01, +3d)")
+		fs.StringVar(&addCmd.Project, "project", "", "project name, e.g. work.api")
+		fs.IntVar(&addCmd.ParentID, "parent", 0, "ID of the parent task")
+		fs.StringVar(&addCmd.Recur, "recur", "", "recurrence rule, e.g. weekly:fri or \"every 2 weeks\"")
+		fs.StringVar(&addCmd.Estimate, "estimate", "", "expected effort, e.g. 3h or 5pt")
+		if err := parseFlags(fs, remainingArgs); err != nil {
 			return err
 		}
 		remainingArgs = fs.Args()
 
 	case "list":
-		cmd = &ListCommand{}
+		listCmd := &ListCommand{}
+		cmd = listCmd
+		fs := flag.NewFlagSet("list", flag.ContinueOnError)
+		fs.StringVar(&listCmd.Sort, "sort", "priority", "sort order (priority, id)")
+		fs.BoolVar(&listCmd.Overdue, "overdue", false, "only show overdue tasks")
+		fs.StringVar(&listCmd.DueBefore, "due-before", "", "only show tasks due before this date")
+		fs.StringVar(&listCmd.Project, "project", "", "only show tasks in this project and its sub-projects")
+		fs.BoolVar(&listCmd.Tree, "tree", false, "show subtasks indented under their parents")
+		fs.BoolVar(&listCmd.Archived, "archived", false, "list archived tasks instead")
+		if err := parseFlags(fs, remainingArgs); err != nil {
+			return err
+		}
+		remainingArgs = fs.Args()
 
 	case "done":
-		cmd = &DoneCommand{}
+		doneCmd := &DoneCommand{}
+		cmd = doneCmd
 		fs := flag.NewFlagSet("done", flag.ContinueOnError)
-		if err := fs.Parse(remainingArgs); err != nil {
+		fs.BoolVar(&doneCmd.CompleteParents, "complete-parent", false, "complete parents whose subtasks are all closed")
+		fs.BoolVar(&doneCmd.Force, "force", false, "complete even if prerequisites are open")
+		if err := parseFlags(fs, remainingArgs); err != nil {
 			return err
 		}
 		remainingArgs = fs.Args()
 
+	case "start":
+		cmd = &StatusCommand{Status: task.StatusInProgress, Verb: "started"}
+
+	case "block":
+		cmd = &StatusCommand{Status: task.StatusBlocked, Verb: "marked as blocked"}
+
+	case "wait":
+		cmd = &StatusCommand{Status: task.StatusWaiting, Verb: "marked as waiting"}
+
+	case "cancel":
+		cmd = &StatusCommand{Status: task.StatusCancelled, Verb: "cancelled"}
+
+	case "reopen":
+		cmd = &StatusCommand{Status: task.StatusPending, Verb: "reopened"}
+
+	case "edit":
+		editCmd := &EditCommand{}
+		cmd = editCmd
+		fs := flag.NewFlagSet("edit", flag.ContinueOnError)
+		fs.StringVar(&editCmd.Description, "desc", "", "new description")
+		fs.StringVar(&editCmd.Priority, "priority", "", "new priority")
+		fs.StringVar(&editCmd.Due, "due", "", "new due date, or none")
+		fs.StringVar(&editCmd.Project, "project", "", "new project, or none")
+		fs.StringVar(&editCmd.Estimate, "estimate", "", "new estimate, e.g. 3h or 5pt, or none")
+		if err := parseFlags(fs, remainingArgs); err != nil {
+			return err
+		}
+		remainingArgs = fs.Args()
+
+	case "priority":
+		cmd = &PriorityCommand{}
+
+	case "tag":
+		cmd = &TagCommand{}
+
+	case "tags":
+		cmd = &TagsCommand{}
+
+	case "parent":
+		cmd = &ParentCommand{}
+
+	case "depend":
+		dependCmd := &DependCommand{}
+		cmd = dependCmd
+		fs := flag.NewFlagSet("depend", flag.ContinueOnError)
+		fs.BoolVar(&dependCmd.Remove, "remove", false, "remove the given dependencies")
+		if err := parseFlags(fs, remainingArgs); err != nil {
+			return err
+		}
+		remainingArgs = fs.Args()
+
+	case "next":
+		nextCmd := &NextCommand{}
+		cmd = nextCmd
+		fs := flag.NewFlagSet("next", flag.ContinueOnError)
+		fs.BoolVar(&nextCmd.All, "all", false, "show every open task in dependency order")
+		if err := parseFlags(fs, remainingArgs); err != nil {
+			return err
+		}
+		remainingArgs = fs.Args()
+
+	case "recur":
+		cmd = &RecurCommand{}
+
+	case "move":
+		cmd = &MoveCommand{}
+
+	case "projects":
+		cmd = &ProjectsCommand{}
+
 	case "del":
-		cmd = &DeleteCommand{}
+		delCmd := &DeleteCommand{}
+		cmd = delCmd
 		fs := flag.NewFlagSet("del", flag.ContinueOnError)
-		if err := fs.Parse(remainingArgs); err != nil {
+		fs.BoolVar(&delCmd.Cascade, "cascade", false, "also delete all subtasks")
+		if err := parseFlags(fs, remainingArgs); err != nil {
 			return err
 		}
 		remainingArgs = fs.Args()
 
 	case "search":
-		cmd = &SearchCommand{}
+		searchCmd := &SearchCommand{}
+		cmd = searchCmd
 		fs := flag.NewFlagSet("search", flag.ContinueOnError)
-		if err := fs.Parse(remainingArgs); err != nil {
+		fs.BoolVar(&searchCmd.IncludeArchive, "include-archive", false, "also search archived tasks")
+		fs.BoolVar(&searchCmd.Regex, "regex", false, "treat the term as a regular expression")
+		fs.BoolVar(&searchCmd.Fuzzy, "fuzzy", false, "match letters in order and tolerate typos")
+		fs.BoolVar(&searchCmd.Word, "word", false, "only match whole words")
+		fs.StringVar(&searchCmd.Where, "where", "", "only search tasks matching this query")
+		if err := parseFlags(fs, remainingArgs); err != nil {
+			return err
+		}
+		remainingArgs = fs.Args()
+
+	case "archive":
+		archiveCmd := &ArchiveCommand{}
+		cmd = archiveCmd
+		fs := flag.NewFlagSet("archive", flag.ContinueOnError)
+		fs.StringVar(&archiveCmd.DoneBefore, "done-before", "", "only archive tasks completed before this date")
+		if err := parseFlags(fs, remainingArgs); err != nil {
+			return err
+		}
+		remainingArgs = fs.Args()
+
+	case "migrate-storage":
+		migrateCmd := &MigrateStorageCommand{}
+		cmd = migrateCmd
+		fs := flag.NewFlagSet("migrate-storage", flag.ContinueOnError)
+		fs.StringVar(&migrateCmd.To, "to", "", "storage backend to migrate to (json, db, log)")
+		if err := parseFlags(fs, remainingArgs); err != nil {
+			return err
+		}
+		remainingArgs = fs.Args()
+
+	case "trash":
+		cmd = &TrashCommand{}
+
+	case "restore":
+		cmd = &RestoreCommand{}
+
+	case "purge":
+		purgeCmd := &PurgeCommand{}
+		cmd = purgeCmd
+		fs := flag.NewFlagSet("purge", flag.ContinueOnError)
+		fs.StringVar(&purgeCmd.OlderThan, "older-than", "", "only purge tasks deleted more than this long ago, e.g. 30d")
+		if err := parseFlags(fs, remainingArgs); err != nil {
+			return err
+		}
+		remainingArgs = fs.Args()
+
+	case "undo":
+		cmd = &UndoCommand{}
+
+	case "redo":
+		cmd = &UndoCommand{Redo: true}
+
+	case "history":
+		historyCmd := &HistoryCommand{}
+		cmd = historyCmd
+		fs := flag.NewFlagSet("history", flag.ContinueOnError)
+		fs.IntVar(&historyCmd.Limit, "limit", 20, "number of entries to show, 0 for all")
+		if err := parseFlags(fs, remainingArgs); err != nil {
+			return err
+		}
+		remainingArgs = fs.Args()
+
+	case "log":
+		cmd = &LogCommand{}
+
+	case "track":
+		cmd = &TrackCommand{}
+
+	case "pomodoro":
+		pomodoroCmd := &PomodoroCommand{}
+		cmd = pomodoroCmd
+		fs := flag.NewFlagSet("pomodoro", flag.ContinueOnError)
+		fs.DurationVar(&pomodoroCmd.Work, "work", 25*time.Minute, "length of a work period")
+		fs.DurationVar(&pomodoroCmd.Break, "break", 5*time.Minute, "length of a break")
+		fs.IntVar(&pomodoroCmd.Cycles, "cycles", 4, "number of work periods")
+		if err := parseFlags(fs, remainingArgs); err != nil {
+			return err
+		}
+		remainingArgs = fs.Args()
+
+	case "show":
+		cmd = &ShowCommand{}
+
+	case "timesheet":
+		timesheetCmd := &TimesheetCommand{}
+		cmd = timesheetCmd
+		fs := flag.NewFlagSet("timesheet", flag.ContinueOnError)
+		fs.BoolVar(&timesheetCmd.Week, "week", false, "show the current week")
+		fs.StringVar(&timesheetCmd.From, "from", "", "start of the period")
+		fs.StringVar(&timesheetCmd.To, "to", "", "end of the period")
+		if err := parseFlags(fs, remainingArgs); err != nil {
+			return err
+		}
+		remainingArgs = fs.Args()
+
+	case "stats":
+		statsCmd := &StatsCommand{}
+		cmd = statsCmd
+		fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+		fs.StringVar(&statsCmd.By, "by", "", "group by project or tag")
+		fs.StringVar(&statsCmd.From, "from", "", "only tasks completed on or after this date")
+		fs.StringVar(&statsCmd.To, "to", "", "only tasks completed on or before this date")
+		if err := parseFlags(fs, remainingArgs); err != nil {
+			return err
+		}
+		remainingArgs = fs.Args()
+
+	case "report":
+		reportCmd := &ReportCommand{}
+		cmd = reportCmd
+		fs := flag.NewFlagSet("report", flag.ContinueOnError)
+		fs.StringVar(&reportCmd.Against, "against", task.AgainstTracked, "measure actual effort as tracked or cycle time")
+		fs.Float64Var(&reportCmd.Threshold, "threshold", 50, "flag tasks that overran their estimate by more than this percentage")
+		if err := parseFlags(fs, remainingArgs); err != nil {
 			return err
 		}
 		remainingArgs = fs.Args()
@@ -63,3 +273,48 @@ func ExecuteCommand(manager *task.TaskManager, args []string) error {
 
 	return cmd.Execute(manager, remainingArgs)
 }
+
+// parseFlags parses flags that may appear anywhere among the positional
+// arguments, e.g. `add "Buy milk" --priority high`. Tokens that look like
+// flags but are not defined on fs are kept as positional arguments, and
+// everything after "--" is positional.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	var flagArgs, positional []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		if !strings.HasPrefix(arg, "-") || name == "" {
+			positional = append(positional, arg)
+			continue
+		}
+
+		name, _, hasValue := strings.Cut(name, "=")
+		f := fs.Lookup(name)
+		if f == nil {
+			positional = append(positional, arg)
+			continue
+		}
+
+		flagArgs = append(flagArgs, arg)
+		if hasValue || isBoolFlag(f) {
+			continue
+		}
+		if i+1 < len(args) {
+			i++
+			flagArgs = append(flagArgs, args[i])
+		}
+	}
+
+	return fs.Parse(append(flagArgs, append([]string{"--"}, positional...)...))
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
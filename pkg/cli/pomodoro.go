@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// countdown shows the time left in a phase of a pomodoro session on a single,
+// continually rewritten line. It reports false if ctx was cancelled, e.g. by
+// Ctrl-C, before the time was up.
+func countdown(ctx context.Context, label string, d time.Duration) bool {
+	deadline := time.Now().Add(d)
+	done := time.NewTimer(d)
+	defer done.Stop()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		fmt.Printf("\r%s  %s left ", label, formatClock(time.Until(deadline)))
+		select {
+		case <-ctx.Done():
+			fmt.Println()
+			return false
+		case <-done.C:
+			fmt.Printf("\r%s  %s left \n", label, formatClock(0))
+			return true
+		case <-ticker.C:
+		}
+	}
+}
+
+// formatClock renders a countdown as "mm:ss", or "h:mm:ss" from an hour up.
+func formatClock(d time.Duration) string {
+	d = max(d, 0).Round(time.Second)
+	h := int(d / time.Hour)
+	m := int(d % time.Hour / time.Minute)
+	s := int(d % time.Minute / time.Second)
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
@@ -0,0 +1,176 @@
+// Package dateparse turns human-friendly date expressions such as "tomorrow",
+// "next fri", "2026-11-01" or "+3d" into calendar dates.
+package dateparse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// Parse interprets input relative to now and returns the start of the resulting day
+// in now's location. Supported forms are:
+//
+//	today, tomorrow, yesterday
+//	mon ... sun, next fri     the next such weekday after today
+//	next week, next month     one week or one month from today
+//	2026-11-01                an absolute date
+//	+3d, -1w, +2m, +1y        an offset in days, weeks, months or years
+//	in 3 days, in 2 weeks     the same offsets spelled out
+func Parse(input string, now time.Time) (time.Time, error) {
+	s := strings.ToLower(strings.Join(strings.Fields(input), " "))
+	if s == "" {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+
+	today := StartOfDay(now)
+
+	switch s {
+	case "today", "now":
+		return today, nil
+	case "tomorrow", "tmr":
+		return today.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "next week":
+		return today.AddDate(0, 0, 7), nil
+	case "next month":
+		return today.AddDate(0, 1, 0), nil
+	case "next year":
+		return today.AddDate(1, 0, 0), nil
+	}
+
+	if day, ok := weekdays[strings.TrimPrefix(s, "next ")]; ok {
+		return nextWeekday(today, day), nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, nil
+	}
+
+	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
+		return parseOffset(today, s)
+	}
+
+	if rest, ok := strings.CutPrefix(s, "in "); ok {
+		return parseSpelledOffset(today, rest)
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date %q (try today, tomorrow, fri, next fri, 2026-11-01 or +3d)", input)
+}
+
+// Ago returns the start of the day that lies the given span before now.
+// Spans are written like offsets without a sign: 30d, 2w, 3m, 1y or "30 days".
+func Ago(input string, now time.Time) (time.Time, error) {
+	s := strings.ToLower(strings.Join(strings.Fields(input), ""))
+	end := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if end <= 0 {
+		return time.Time{}, fmt.Errorf("invalid duration %q (try 30d, 2w, 3m or 1y)", input)
+	}
+
+	n, err := strconv.Atoi(s[:end])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid duration %q", input)
+	}
+
+	return addUnit(StartOfDay(now), -n, normalizeUnit(s[end:]), input)
+}
+
+// StartOfDay returns midnight at the beginning of t's day in t's location.
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// DaysBetween returns the number of calendar days from a to b,
+// negative when b is before a.
+func DaysBetween(a, b time.Time) int {
+	a, b = StartOfDay(a), StartOfDay(b.In(a.Location()))
+	// Compare in UTC so daylight saving changes don't skew the count
+	ua := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	ub := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(ub.Sub(ua).Hours() / 24)
+}
+
+func nextWeekday(today time.Time, day time.Weekday) time.Time {
+	delta := (int(day) - int(today.Weekday()) + 7) % 7
+	if delta == 0 {
+		delta = 7
+	}
+	return today.AddDate(0, 0, delta)
+}
+
+// parseOffset handles compact offsets such as "+3d" or "-2w".
+func parseOffset(today time.Time, s string) (time.Time, error) {
+	sign := 1
+	if s[0] == '-' {
+		sign = -1
+	}
+	body := s[1:]
+	if len(body) < 2 {
+		return time.Time{}, fmt.Errorf("invalid date offset %q", s)
+	}
+
+	n, err := strconv.Atoi(body[:len(body)-1])
+	if err != nil || n < 0 {
+		return time.Time{}, fmt.Errorf("invalid date offset %q", s)
+	}
+
+	return addUnit(today, sign*n, body[len(body)-1:], s)
+}
+
+// parseSpelledOffset handles offsets such as "3 days" or "2 weeks".
+func parseSpelledOffset(today time.Time, s string) (time.Time, error) {
+	parts := strings.Fields(s)
+	if len(parts) != 2 {
+		return time.Time{}, fmt.Errorf("invalid date offset %q", "in "+s)
+	}
+
+	n, err := strconv.Atoi(parts[0])
+	if err != nil || n < 0 {
+		return time.Time{}, fmt.Errorf("invalid date offset %q", "in "+s)
+	}
+
+	return addUnit(today, n, normalizeUnit(parts[1]), "in "+s)
+}
+
+// normalizeUnit maps spelled-out units such as "day" or "weeks" to the
+// single-letter units understood by addUnit.
+func normalizeUnit(unit string) string {
+	switch strings.TrimSuffix(unit, "s") {
+	case "d", "day":
+		return "d"
+	case "w", "week":
+		return "w"
+	case "m", "month":
+		return "m"
+	case "y", "year":
+		return "y"
+	}
+	return unit
+}
+
+func addUnit(today time.Time, n int, unit, original string) (time.Time, error) {
+	switch unit {
+	case "d":
+		return today.AddDate(0, 0, n), nil
+	case "w":
+		return today.AddDate(0, 0, 7*n), nil
+	case "m":
+		return today.AddDate(0, n, 0), nil
+	case "y":
+		return today.AddDate(n, 0, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date offset %q (units are d, w, m, y)", original)
+}
@@ -0,0 +1,106 @@
+package dateparse
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParse tests the supported date expressions against a fixed clock
+func TestParse(t *testing.T) {
+	// Wednesday
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{"today", "2026-10-14"},
+		{"tomorrow", "2026-10-15"},
+		{"yesterday", "2026-10-13"},
+		{"fri", "2026-10-16"},
+		{"next fri", "2026-10-16"},
+		{"Wednesday", "2026-10-21"},
+		{"next week", "2026-10-21"},
+		{"2026-11-01", "2026-11-01"},
+		{"+3d", "2026-10-17"},
+		{"-1d", "2026-10-13"},
+		{"+2w", "2026-10-28"},
+		{"+1m", "2026-11-14"},
+		{"in 3 days", "2026-10-17"},
+		{"in 1 week", "2026-10-21"},
+		{"in 2 w", "2026-10-28"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			got, err := Parse(c.input, now)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got.Format("2006-01-02") != c.want {
+				t.Errorf("Expected %s, got %s", c.want, got.Format("2006-01-02"))
+			}
+			if !got.Equal(StartOfDay(got)) {
+				t.Errorf("Expected start of day, got %s", got)
+			}
+		})
+	}
+
+	t.Run("Rejects unknown input", func(t *testing.T) {
+		for _, input := range []string{"", "someday", "+3x", "+d", "2026-13-01", "in many days"} {
+			if _, err := Parse(input, now); err == nil {
+				t.Errorf("Expected error for %q", input)
+			}
+		}
+	})
+}
+
+// TestAgo tests spans counted back from now
+func TestAgo(t *testing.T) {
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{"30d", "2026-09-14"},
+		{"2w", "2026-09-30"},
+		{"1m", "2026-09-14"},
+		{"1y", "2025-10-14"},
+		{"10 days", "2026-10-04"},
+		{"2 weeks", "2026-09-30"},
+		{"1 year", "2025-10-14"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			got, err := Ago(c.input, now)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got.Format("2006-01-02") != c.want {
+				t.Errorf("Expected %s, got %s", c.want, got.Format("2006-01-02"))
+			}
+		})
+	}
+
+	t.Run("Rejects invalid spans", func(t *testing.T) {
+		for _, input := range []string{"", "d", "-3d", "3x", "soon"} {
+			if _, err := Ago(input, now); err == nil {
+				t.Errorf("Expected error for %q", input)
+			}
+		}
+	})
+}
+
+// TestDaysBetween tests calendar day differences
+func TestDaysBetween(t *testing.T) {
+	now := time.Date(2026, 10, 14, 23, 0, 0, 0, time.UTC)
+
+	if d := DaysBetween(now, time.Date(2026, 10, 15, 1, 0, 0, 0, time.UTC)); d != 1 {
+		t.Errorf("Expected 1, got %d", d)
+	}
+	if d := DaysBetween(now, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)); d != -2 {
+		t.Errorf("Expected -2, got %d", d)
+	}
+}
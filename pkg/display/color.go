@@ -0,0 +1,32 @@
+package display
+
+import "os"
+
+// SGR color codes. All codes have the same length so painted cells keep
+// the same width overhead and tabwriter columns stay aligned.
+const (
+	colorDefault = "39"
+	colorRed     = "31"
+	colorYellow  = "33"
+	colorMagenta = "35"
+)
+
+// colorEnabled reports whether stdout is a terminal that should receive ANSI colors.
+// Setting NO_COLOR disables colors entirely.
+var colorEnabled = detectColor()
+
+func detectColor() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// paint wraps s in the given color when colors are enabled.
+func paint(color, s string) string {
+	if !colorEnabled {
+		return s
+	}
+	return "\x1b[" + color + "m" + s + "\x1b[0m"
+}
@@ -3,20 +3,27 @@ package display
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/amit9838/taskmanager/internal/task"
+	"github.com/amit9838/taskmanager/pkg/dateparse"
 )
 
 func PrintTasks(tasks []task.Task) {
+	now := time.Now()
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "ID\tStatus\tDescription\tCreated\tUpdated")
-	fmt.Fprintln(w, "--\t------\t-----------\t-------\t-------")
+	fmt.Fprintln(w, "ID\tStatus\tPriority\tDescription\tProject\tTags\tDue\tCreated\tUpdated")
+	fmt.Fprintln(w, "--\t------\t--------\t-----------\t-------\t----\t---\t-------\t-------")
 
 	for _, t := range tasks {
-		status := "[ ]"
-		if t.Done {
-			status = "[x]"
+		status := formatStatus(t.Status)
+
+		priorityStr := "-"
+		if t.Priority != task.PriorityNone {
+			priorityStr = t.Priority.String()
 		}
 
 		createdStr := t.CreatedAt.Format("2006-01-02")
@@ -25,18 +32,556 @@ func PrintTasks(tasks []task.Task) {
 			updatedStr = t.UpdatedAt.Format("2006-01-02")
 		}
 
+		projectStr := "-"
+		if t.Project != "" {
+			projectStr = t.Project
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			t.ID, status, priorityStr, t.Description, projectStr, formatTags(t.Tags), formatDue(t, now), createdStr, updatedStr)
+	}
+	w.Flush()
+}
+
+var statusSymbols = map[task.TaskStatus]string{
+	task.StatusPending:    "[ ]",
+	task.StatusInProgress: "[>]",
+	task.StatusBlocked:    "[!]",
+	task.StatusWaiting:    "[~]",
+	task.StatusDone:       "[x]",
+	task.StatusCancelled:  "[-]",
+}
+
+// formatStatus renders a status as its checkbox symbol followed by its name,
+// e.g. "[>] in-progress".
+func formatStatus(status task.TaskStatus) string {
+	symbol, ok := statusSymbols[status]
+	if !ok {
+		symbol = "[?]"
+	}
+	return symbol + " " + string(status)
+}
+
+func formatTags(tags []string) string {
+	if len(tags) == 0 {
+		return "-"
+	}
+	return "+" + strings.Join(tags, " +")
+}
+
+// formatDue renders the due date relative to now ("today", "in 2d", "overdue 1d"),
+// highlighting overdue tasks. Completed tasks show the plain date.
+func formatDue(t task.Task, now time.Time) string {
+	if t.DueAt == nil {
+		return paint(colorDefault, "-")
+	}
+	if !t.IsOpen() {
+		return paint(colorDefault, t.DueAt.Format("2006-01-02"))
+	}
+
+	days := dateparse.DaysBetween(now, *t.DueAt)
+	switch {
+	case days < 0:
+		return paint(colorRed, fmt.Sprintf("overdue %dd", -days))
+	case days == 0:
+		return paint(colorYellow, "today")
+	default:
+		return paint(colorDefault, fmt.Sprintf("in %dd", days))
+	}
+}
+
+// PrintSearchResults prints search results in the order given, with the matched
+// parts of each description highlighted. The description comes last so that
+// highlighting does not upset the column alignment.
+func PrintSearchResults(results []task.SearchResult) {
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tStatus\tPriority\tProject\tTags\tDue\tDescription")
+	fmt.Fprintln(w, "--\t------\t--------\t-------\t----\t---\t-----------")
+
+	for _, r := range results {
+		t := r.Task
+		priorityStr := "-"
+		if t.Priority != task.PriorityNone {
+			priorityStr = t.Priority.String()
+		}
+		projectStr := "-"
+		if t.Project != "" {
+			projectStr = t.Project
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			t.ID, formatStatus(t.Status), priorityStr, projectStr, formatTags(t.Tags), formatDue(t, now), highlight(t.Description, r.Spans))
+	}
+	w.Flush()
+}
+
+// highlight paints the given spans of s.
+func highlight(s string, spans []task.Span) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		b.WriteString(s[last:span.Start])
+		b.WriteString(paint(colorMagenta, s[span.Start:span.End]))
+		last = span.End
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// PrintTaskTree prints tasks with subtasks indented under their parents.
+// Parents show how many of their direct subtasks are done. Tasks whose parent
+// is not in the list are shown at the top level; the given order is kept among siblings.
+func PrintTaskTree(tasks []task.Task) {
+	now := time.Now()
+	total, done := task.ChildCounts(tasks)
+
+	present := map[int]bool{}
+	for _, t := range tasks {
+		present[t.ID] = true
+	}
+	children := map[int][]task.Task{}
+	var roots []task.Task
+	for _, t := range tasks {
+		if t.ParentID != 0 && present[t.ParentID] {
+			children[t.ParentID] = append(children[t.ParentID], t)
+		} else {
+			roots = append(roots, t)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tStatus\tPriority\tTask\tDue")
+	fmt.Fprintln(w, "--\t------\t--------\t----\t---")
+
+	var walk func(nodes []task.Task, depth int)
+	walk = func(nodes []task.Task, depth int) {
+		for _, t := range nodes {
+			label := t.Description
+			if depth > 0 {
+				label = strings.Repeat("  ", depth-1) + "└─ " + label
+			}
+			if n := total[t.ID]; n > 0 {
+				label += fmt.Sprintf(" (%d/%d)", done[t.ID], n)
+			}
+
+			priorityStr := "-"
+			if t.Priority != task.PriorityNone {
+				priorityStr = t.Priority.String()
+			}
+
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
+				t.ID, formatStatus(t.Status), priorityStr, label, formatDue(t, now))
+			walk(children[t.ID], depth+1)
+		}
+	}
+	walk(roots, 0)
+	w.Flush()
+}
+
+// PrintPlan prints open tasks in dependency order along with the prerequisites
+// that still block each of them.
+func PrintPlan(plan []task.PlanStep) {
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tID\tStatus\tPriority\tDescription\tDue\tBlocked by")
+	fmt.Fprintln(w, "-\t--\t------\t--------\t-----------\t---\t----------")
+
+	for i, step := range plan {
+		t := step.Task
+		priorityStr := "-"
+		if t.Priority != task.PriorityNone {
+			priorityStr = t.Priority.String()
+		}
+
+		blockedStr := "-"
+		if len(step.BlockedBy) > 0 {
+			blockedStr = task.FormatIDs(step.BlockedBy)
+		}
+
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			i+1, t.ID, formatStatus(t.Status), priorityStr, t.Description, formatDue(t, now), blockedStr)
+	}
+	w.Flush()
+}
+
+// PrintRecurring prints the open instance of each recurring series.
+func PrintRecurring(tasks []task.Task) {
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSeries\tRule\tDescription\tDue")
+	fmt.Fprintln(w, "--\t------\t----\t-----------\t---")
+
+	for _, t := range tasks {
+		series := t.SeriesID
+		if series == 0 {
+			series = t.ID
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\n",
+			t.ID, series, t.Recur, t.Description, formatDue(t, now))
+	}
+	w.Flush()
+}
+
+// PrintHistory prints journal entries with their time and the tasks they touched.
+func PrintHistory(entries []task.JournalEntry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tTime\tOperation\tTasks\tState")
+	fmt.Fprintln(w, "-\t----\t---------\t-----\t-----")
+
+	for _, e := range entries {
+		state := "applied"
+		if e.Undone {
+			state = "undone"
+		}
 		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
-			t.ID, status, t.Description, createdStr, updatedStr)
+			e.Seq, e.Time.Local().Format("2006-01-02 15:04:05"), e.Op, task.FormatIDs(e.TaskIDs()), state)
+	}
+	w.Flush()
+}
+
+// PrintTaskLog prints a task's timestamps followed by its recorded changes.
+func PrintTaskLog(id int, log task.TaskLog) {
+	const layout = "2006-01-02 15:04:05"
+
+	if log.Task == nil {
+		fmt.Printf("Task %d (purged)\n", id)
+	} else {
+		t := log.Task
+		where := ""
+		switch {
+		case log.Archived:
+			where = " (archived)"
+		case t.IsDeleted():
+			where = " (in the trash)"
+		}
+		fmt.Printf("Task %d: %s%s\n", id, t.Description, where)
+		fmt.Printf("  Status:    %s\n", formatStatus(t.Status))
+		fmt.Printf("  Created:   %s\n", t.CreatedAt.Local().Format(layout))
+		if !t.UpdatedAt.IsZero() {
+			fmt.Printf("  Updated:   %s\n", t.UpdatedAt.Local().Format(layout))
+		}
+		if t.StartedAt != nil {
+			fmt.Printf("  Started:   %s\n", t.StartedAt.Local().Format(layout))
+		}
+		if t.CompletedAt != nil {
+			fmt.Printf("  Completed: %s\n", t.CompletedAt.Local().Format(layout))
+		}
+	}
+
+	fmt.Println()
+	if len(log.Changes) == 0 {
+		fmt.Println("No changes recorded.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Time\tChange\tOperation\tBy\tDetails")
+	fmt.Fprintln(w, "----\t------\t---------\t--\t-------")
+
+	for _, c := range log.Changes {
+		by := c.By
+		if by == "" {
+			by = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			c.Time.Local().Format(layout), c.Kind, c.Op, by, formatFieldChanges(c.Fields))
 	}
 	w.Flush()
 }
 
+// PrintEstimateReport prints the estimate accuracy per project, followed by
+// the tasks that overran their estimate by more than threshold percent.
+func PrintEstimateReport(report task.EstimateReport, threshold float64) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Project\tTasks\tActual/Estimate\tPoint tasks\tPer point")
+	fmt.Fprintln(w, "-------\t-----\t---------------\t-----------\t---------")
+
+	for _, p := range report.Projects {
+		project := p.Project
+		if project == "" {
+			project = "(none)"
+		}
+		ratio := "-"
+		if p.Tasks > 0 {
+			ratio = formatRatio(p.Ratio)
+		}
+		perPoint := "-"
+		if p.PerPoint > 0 {
+			perPoint = FormatDuration(p.PerPoint)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%s\n", project, p.Tasks, ratio, p.PointTasks, perPoint)
+	}
+	w.Flush()
+
+	fmt.Println()
+	if len(report.Overruns) == 0 {
+		fmt.Printf("No task overran its estimate by more than %g%%.\n", threshold)
+		return
+	}
+
+	fmt.Printf("Tasks that overran their estimate by more than %g%%:\n", threshold)
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDescription\tProject\tEstimate\tActual\tActual/Estimate")
+	fmt.Fprintln(w, "--\t-----------\t-------\t--------\t------\t---------------")
+	for _, r := range report.Overruns {
+		project := "-"
+		if r.Task.Project != "" {
+			project = r.Task.Project
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			r.Task.ID, r.Task.Description, project, r.Task.Estimate, FormatDuration(r.Actual), paint(colorRed, formatRatio(r.Ratio)))
+	}
+	w.Flush()
+}
+
+// formatRatio renders an actual/estimate ratio, e.g. "1.25x".
+func formatRatio(ratio float64) string {
+	return fmt.Sprintf("%.2fx", ratio)
+}
+
+// PrintTask prints every field of a single task, with the time tracked on it
+// and its pomodoro sessions.
+func PrintTask(t task.Task) {
+	const layout = "2006-01-02 15:04:05"
+	now := time.Now()
+
+	fmt.Printf("Task %d: %s\n", t.ID, t.Description)
+	fmt.Printf("  Status:     %s\n", formatStatus(t.Status))
+	if t.Priority != task.PriorityNone {
+		fmt.Printf("  Priority:   %s\n", t.Priority)
+	}
+	if t.Project != "" {
+		fmt.Printf("  Project:    %s\n", t.Project)
+	}
+	if len(t.Tags) > 0 {
+		fmt.Printf("  Tags:       %s\n", formatTags(t.Tags))
+	}
+	if t.DueAt != nil {
+		fmt.Printf("  Due:        %s (%s)\n", t.DueAt.Local().Format("2006-01-02 15:04"), formatDue(t, now))
+	}
+	if t.ParentID != 0 {
+		fmt.Printf("  Parent:     %d\n", t.ParentID)
+	}
+	if len(t.DependsOn) > 0 {
+		fmt.Printf("  Depends on: %s\n", task.FormatIDs(t.DependsOn))
+	}
+	if t.Recur != nil {
+		fmt.Printf("  Recurs:     %s\n", t.Recur)
+	}
+
+	fmt.Printf("  Created:    %s\n", t.CreatedAt.Local().Format(layout))
+	if !t.UpdatedAt.IsZero() {
+		fmt.Printf("  Updated:    %s\n", t.UpdatedAt.Local().Format(layout))
+	}
+	if t.StartedAt != nil {
+		fmt.Printf("  Started:    %s\n", t.StartedAt.Local().Format(layout))
+	}
+	if t.CompletedAt != nil {
+		fmt.Printf("  Completed:  %s\n", t.CompletedAt.Local().Format(layout))
+	}
+
+	if t.Estimate != nil {
+		fmt.Printf("  Estimate:   %s\n", t.Estimate)
+	}
+	if len(t.Tracked) > 0 {
+		tracked := FormatDuration(t.TrackedTime(now))
+		if running, ok := t.Running(); ok {
+			tracked += fmt.Sprintf(" (running since %s)", running.Start.Local().Format("15:04"))
+		}
+		if t.Estimate != nil && !t.Estimate.IsPoints() {
+			tracked += fmt.Sprintf(", %.0f%% of the estimate", 100*float64(t.TrackedTime(now))/float64(t.Estimate.Duration))
+		}
+		fmt.Printf("  Tracked:    %s\n", tracked)
+	}
+	if completed, interrupted := t.Pomodoros(); completed+interrupted > 0 {
+		fmt.Printf("  Pomodoros:  %d completed, %d interrupted\n", completed, interrupted)
+	}
+}
+
+// formatFieldChanges renders field changes as "field: old -> new" pairs.
+func formatFieldChanges(fields []task.FieldChange) string {
+	if len(fields) == 0 {
+		return "-"
+	}
+
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		switch {
+		case f.Old == "":
+			parts[i] = fmt.Sprintf("%s: %s", f.Field, f.New)
+		case f.New == "":
+			parts[i] = fmt.Sprintf("%s: %s -> (none)", f.Field, f.Old)
+		default:
+			parts[i] = fmt.Sprintf("%s: %s -> %s", f.Field, f.Old, f.New)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
 func PrintTasksSimple(tasks []task.Task) {
 	for _, t := range tasks {
-		status := "[ ]"
-		if t.Done {
-			status = "[x]"
+		fmt.Printf("%s %d: %s\n", statusSymbols[t.Status], t.ID, t.Description)
+	}
+}
+
+// PrintTrash prints deleted tasks with the time they were moved to the trash.
+func PrintTrash(tasks []task.Task) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tStatus\tDescription\tProject\tDeleted")
+	fmt.Fprintln(w, "--\t------\t-----------\t-------\t-------")
+
+	for _, t := range tasks {
+		project := t.Project
+		if project == "" {
+			project = "-"
 		}
-		fmt.Printf("%s %d: %s\n", status, t.ID, t.Description)
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
+			t.ID, formatStatus(t.Status), t.Description, project, t.DeletedAt.Local().Format("2006-01-02 15:04"))
+	}
+	w.Flush()
+}
+
+func PrintTagCounts(counts []task.TagCount) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Tag\tOpen\tDone")
+	fmt.Fprintln(w, "---\t----\t----")
+
+	for _, c := range counts {
+		fmt.Fprintf(w, "+%s\t%d\t%d\n", c.Tag, c.Open, c.Done)
+	}
+	w.Flush()
+}
+
+// PrintCycleTimes prints lead and cycle time distributions, one row per group.
+// by names the grouping ("project", "tag" or empty) for the header.
+func PrintCycleTimes(stats []task.CycleTimeStats, by string) {
+	header := "Group"
+	switch by {
+	case task.GroupByProject:
+		header = "Project"
+	case task.GroupByTag:
+		header = "Tag"
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tDone\tLead median\tLead p90\tStarted\tCycle median\tCycle p90\n", header)
+	fmt.Fprintf(w, "%s\t----\t-----------\t--------\t-------\t------------\t---------\n", strings.Repeat("-", len(header)))
+
+	for _, s := range stats {
+		group := s.Group
+		switch {
+		case group == "":
+			group = "(none)"
+		case by == task.GroupByTag:
+			group = "+" + group
+		}
+
+		cycleMedian, cycleP90 := "-", "-"
+		if s.Cycle.Count > 0 {
+			cycleMedian, cycleP90 = FormatDuration(s.Cycle.Median), FormatDuration(s.Cycle.P90)
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%d\t%s\t%s\n",
+			group, s.Lead.Count, FormatDuration(s.Lead.Median), FormatDuration(s.Lead.P90), s.Cycle.Count, cycleMedian, cycleP90)
+	}
+	w.Flush()
+}
+
+// PrintTimesheet prints tracked time per day and task, followed by totals per
+// day and per project. Entries must be sorted by day.
+func PrintTimesheet(entries []task.TimeEntry, from, to time.Time) {
+	fmt.Printf("Timesheet %s to %s\n\n", from.Format("2006-01-02"), to.AddDate(0, 0, -1).Format("2006-01-02"))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Day\tProject\tID\tDescription\tTime")
+	fmt.Fprintln(w, "---\t-------\t--\t-----------\t----")
+
+	var days []time.Time
+	perDay := map[time.Time]time.Duration{}
+	perProject := map[string]time.Duration{}
+	var total time.Duration
+
+	for _, e := range entries {
+		day := ""
+		if _, seen := perDay[e.Day]; !seen {
+			days = append(days, e.Day)
+			day = e.Day.Format("Mon 2006-01-02")
+		}
+		project := e.Project
+		if project == "" {
+			project = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", day, project, e.TaskID, e.Description, FormatDuration(e.Duration))
+
+		perDay[e.Day] += e.Duration
+		perProject[e.Project] += e.Duration
+		total += e.Duration
+	}
+	w.Flush()
+
+	fmt.Println("\nPer day:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, day := range days {
+		fmt.Fprintf(w, "  %s\t%s\n", day.Format("Mon 2006-01-02"), FormatDuration(perDay[day]))
+	}
+	w.Flush()
+
+	projects := make([]string, 0, len(perProject))
+	for project := range perProject {
+		projects = append(projects, project)
+	}
+	sort.Strings(projects)
+
+	fmt.Println("\nPer project:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, project := range projects {
+		name := project
+		if name == "" {
+			name = "(none)"
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", name, FormatDuration(perProject[project]))
+	}
+	w.Flush()
+
+	fmt.Printf("\nTotal: %s\n", FormatDuration(total))
+}
+
+// FormatDuration renders a duration with its two largest units, e.g. "2d 4h" or "35m".
+func FormatDuration(d time.Duration) string {
+	if d > 0 && d < 30*time.Second {
+		return "<1m"
+	}
+	d = d.Round(time.Minute)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+
+	switch {
+	case days > 0 && hours > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case days > 0:
+		return fmt.Sprintf("%dd", days)
+	case hours > 0 && minutes > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+func PrintProjectTree(projects []*task.ProjectNode) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Project\tOpen\tDone\tComplete")
+	fmt.Fprintln(w, "-------\t----\t----\t--------")
+	printProjectNodes(w, projects, 0)
+	w.Flush()
+}
+
+func printProjectNodes(w *tabwriter.Writer, nodes []*task.ProjectNode, depth int) {
+	for _, n := range nodes {
+		fmt.Fprintf(w, "%s%s\t%d\t%d\t%.0f%%\n",
+			strings.Repeat("  ", depth), n.Name, n.Open, n.Done, n.PercentDone())
+		printProjectNodes(w, n.Children, depth+1)
 	}
 }
@@ -0,0 +1,92 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Expr is a node of a parsed query.
+type Expr interface {
+	// Pos returns the byte offset in the query where the node starts.
+	Pos() int
+	// String renders the node back as query text, parenthesizing every
+	// binary expression.
+	String() string
+}
+
+// Binary joins two expressions with "and" or "or".
+type Binary struct {
+	Op          string
+	Left, Right Expr
+}
+
+// Not negates an expression.
+type Not struct {
+	At int
+	X  Expr
+}
+
+// Compare tests a field of the task, as in "priority>=high".
+type Compare struct {
+	Field    string
+	Op       string
+	Value    string
+	FieldPos int
+	OpPos    int
+	ValuePos int
+}
+
+// Text matches tasks whose description contains it, ignoring case. Bare words
+// and quoted strings outside a comparison are text.
+type Text struct {
+	At   int
+	Text string
+}
+
+// Tag matches tasks carrying a tag, written "+tag". A "-tag" is parsed as a
+// Not around a Tag.
+type Tag struct {
+	At  int
+	Tag string
+}
+
+func (e *Binary) Pos() int  { return e.Left.Pos() }
+func (e *Not) Pos() int     { return e.At }
+func (e *Compare) Pos() int { return e.FieldPos }
+func (e *Text) Pos() int    { return e.At }
+func (e *Tag) Pos() int     { return e.At }
+
+func (e *Binary) String() string {
+	return "(" + e.Left.String() + " " + e.Op + " " + e.Right.String() + ")"
+}
+
+func (e *Not) String() string {
+	return "not " + e.X.String()
+}
+
+func (e *Compare) String() string {
+	return e.Field + e.Op + quoteIfNeeded(e.Value)
+}
+
+func (e *Text) String() string {
+	// A leading + or - would turn the word into a tag
+	if strings.HasPrefix(e.Text, "+") || strings.HasPrefix(e.Text, "-") {
+		return strconv.Quote(e.Text)
+	}
+	return quoteIfNeeded(e.Text)
+}
+
+func (e *Tag) String() string {
+	return "+" + e.Tag
+}
+
+// quoteIfNeeded quotes s unless it reads back as the same plain word.
+func quoteIfNeeded(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n()\":=!<>~") {
+		return strconv.Quote(s)
+	}
+	if _, ok := keywords[strings.ToLower(s)]; ok {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,275 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amit9838/taskmanager/internal/task"
+	"github.com/amit9838/taskmanager/pkg/dateparse"
+)
+
+// matcher evaluates a compiled expression against a task.
+type matcher func(t task.Task) bool
+
+// field describes a field that conditions can test.
+type field struct {
+	// ops lists the operators the field supports besides ":", "=" and "!=".
+	ops string
+	// compile checks a value and returns a matcher for op, which is never
+	// "=" or "!=": those are reduced to ":" and its negation.
+	compile func(op, value string, now time.Time) (matcher, error)
+}
+
+var fields = map[string]field{
+	"status":    {compile: compileStatus},
+	"priority":  {ops: "< <= > >=", compile: compilePriority},
+	"tag":       {compile: compileTag},
+	"project":   {compile: compileProject},
+	"desc":      {ops: "~", compile: compileDesc},
+	"due":       {ops: "< <= > >=", compile: compileDate(func(t task.Task) *time.Time { return t.DueAt })},
+	"created":   {ops: "< <= > >=", compile: compileDate(func(t task.Task) *time.Time { return &t.CreatedAt })},
+	"updated":   {ops: "< <= > >=", compile: compileDate(func(t task.Task) *time.Time { return &t.UpdatedAt })},
+	"completed": {ops: "< <= > >=", compile: compileDate(func(t task.Task) *time.Time { return t.CompletedAt })},
+	"id":        {ops: "< <= > >=", compile: compileID(func(t task.Task) int { return t.ID })},
+	"parent":    {compile: compileID(func(t task.Task) int { return t.ParentID })},
+}
+
+// aliases maps alternative field names to the names in fields.
+var aliases = map[string]string{
+	"description": "desc",
+	"pri":         "priority",
+	"tags":        "tag",
+	"proj":        "project",
+}
+
+// compile checks every condition of expr and turns it into a matcher.
+func compile(input string, expr Expr, now time.Time) (matcher, error) {
+	switch e := expr.(type) {
+	case *Binary:
+		left, err := compile(input, e.Left, now)
+		if err != nil {
+			return nil, err
+		}
+		right, err := compile(input, e.Right, now)
+		if err != nil {
+			return nil, err
+		}
+		if e.Op == "or" {
+			return func(t task.Task) bool { return left(t) || right(t) }, nil
+		}
+		return func(t task.Task) bool { return left(t) && right(t) }, nil
+
+	case *Not:
+		x, err := compile(input, e.X, now)
+		if err != nil {
+			return nil, err
+		}
+		return func(t task.Task) bool { return !x(t) }, nil
+
+	case *Text:
+		return containsText(e.Text), nil
+
+	case *Tag:
+		tag, err := task.NormalizeTag(e.Tag)
+		if err != nil {
+			return nil, errorAt(input, e.At, "%v", err)
+		}
+		return func(t task.Task) bool { return t.HasTag(tag) }, nil
+
+	case *Compare:
+		return compileCompare(input, e, now)
+	}
+
+	return nil, errorAt(input, expr.Pos(), "unsupported expression %s", expr)
+}
+
+func compileCompare(input string, e *Compare, now time.Time) (matcher, error) {
+	name := e.Field
+	if alias, ok := aliases[name]; ok {
+		name = alias
+	}
+	f, ok := fields[name]
+	if !ok {
+		return nil, errorAt(input, e.FieldPos, "unknown field %q (use %s)", e.Field, strings.Join(fieldNames(), ", "))
+	}
+
+	op := e.Op
+	switch op {
+	case ":", "=", "!=":
+		op = ":"
+	default:
+		if !strings.Contains(" "+f.ops+" ", " "+op+" ") {
+			return nil, errorAt(input, e.OpPos, "%s does not support %q (use %s)", e.Field, e.Op, strings.Join(opsOf(f), " "))
+		}
+	}
+
+	match, err := f.compile(op, e.Value, now)
+	if err != nil {
+		return nil, errorAt(input, e.ValuePos, "%v", err)
+	}
+	if e.Op == "!=" {
+		return func(t task.Task) bool { return !match(t) }, nil
+	}
+	return match, nil
+}
+
+func compileStatus(_, value string, _ time.Time) (matcher, error) {
+	switch v := strings.ToLower(value); v {
+	case "open":
+		return func(t task.Task) bool { return t.IsOpen() }, nil
+	case "closed":
+		return func(t task.Task) bool { return !t.IsOpen() }, nil
+	default:
+		for _, status := range task.Statuses {
+			if v == string(status) {
+				return func(t task.Task) bool { return t.Status == status }, nil
+			}
+		}
+	}
+
+	names := []string{"open", "closed"}
+	for _, status := range task.Statuses {
+		names = append(names, string(status))
+	}
+	return nil, fmt.Errorf("invalid status %q (use one of: %s)", value, strings.Join(names, ", "))
+}
+
+func compilePriority(op, value string, _ time.Time) (matcher, error) {
+	p, err := task.ParsePriority(value)
+	if err != nil {
+		return nil, err
+	}
+	return func(t task.Task) bool { return compareInts(int(t.Priority), op, int(p)) }, nil
+}
+
+func compileTag(_, value string, _ time.Time) (matcher, error) {
+	if strings.EqualFold(value, "none") {
+		return func(t task.Task) bool { return len(t.Tags) == 0 }, nil
+	}
+
+	tag, err := task.NormalizeTag(strings.TrimPrefix(value, "+"))
+	if err != nil {
+		return nil, err
+	}
+	return func(t task.Task) bool { return t.HasTag(tag) }, nil
+}
+
+func compileProject(_, value string, _ time.Time) (matcher, error) {
+	if strings.EqualFold(value, "none") {
+		return func(t task.Task) bool { return t.Project == "" }, nil
+	}
+
+	project, err := task.NormalizeProject(value)
+	if err != nil {
+		return nil, err
+	}
+	return func(t task.Task) bool { return t.InProject(project) }, nil
+}
+
+func compileDesc(_, value string, _ time.Time) (matcher, error) {
+	if value == "" {
+		return nil, fmt.Errorf("empty text")
+	}
+	return containsText(value), nil
+}
+
+// compileDate returns a compiler for a date field. Dates compare by day: the
+// value names a whole day, so due:fri matches any time on Friday and due<fri
+// anything before it. A task without the date only matches ":none".
+func compileDate(get func(task.Task) *time.Time) func(op, value string, now time.Time) (matcher, error) {
+	return func(op, value string, now time.Time) (matcher, error) {
+		if strings.EqualFold(value, "none") {
+			if op != ":" {
+				return nil, fmt.Errorf("none can only be tested with : or !=")
+			}
+			return func(t task.Task) bool {
+				d := get(t)
+				return d == nil || d.IsZero()
+			}, nil
+		}
+
+		day, err := dateparse.Parse(value, now)
+		if err != nil {
+			return nil, err
+		}
+		next := day.AddDate(0, 0, 1)
+
+		var in func(d time.Time) bool
+		switch op {
+		case ":":
+			in = func(d time.Time) bool { return !d.Before(day) && d.Before(next) }
+		case "<":
+			in = func(d time.Time) bool { return d.Before(day) }
+		case "<=":
+			in = func(d time.Time) bool { return d.Before(next) }
+		case ">":
+			in = func(d time.Time) bool { return !d.Before(next) }
+		case ">=":
+			in = func(d time.Time) bool { return !d.Before(day) }
+		}
+
+		return func(t task.Task) bool {
+			d := get(t)
+			return d != nil && !d.IsZero() && in(*d)
+		}, nil
+	}
+}
+
+// compileID returns a compiler for a field holding a task ID, where 0 means none.
+func compileID(get func(task.Task) int) func(op, value string, now time.Time) (matcher, error) {
+	return func(op, value string, _ time.Time) (matcher, error) {
+		id := 0
+		if !strings.EqualFold(value, "none") {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid task ID %q", value)
+			}
+			id = n
+		}
+		return func(t task.Task) bool { return compareInts(get(t), op, id) }, nil
+	}
+}
+
+// containsText matches tasks whose description contains text, ignoring case.
+func containsText(text string) matcher {
+	text = strings.ToLower(text)
+	return func(t task.Task) bool {
+		return strings.Contains(strings.ToLower(t.Description), text)
+	}
+}
+
+func compareInts(a int, op string, b int) bool {
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return a == b
+}
+
+// fieldNames lists the field names in alphabetical order.
+func fieldNames() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// opsOf lists the operators a field supports.
+func opsOf(f field) []string {
+	ops := []string{":", "=", "!="}
+	if f.ops != "" {
+		ops = append(ops, strings.Fields(f.ops)...)
+	}
+	return ops
+}
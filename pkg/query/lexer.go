@@ -0,0 +1,134 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+)
+
+// token is a lexical unit of a query. Pos is its byte offset in the input.
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// describe renders the token for error messages.
+func (t token) describe() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return `"` + t.text + `"`
+}
+
+// operators lists the comparison operators, longest first so that "<=" is
+// not read as "<" followed by "=".
+var operators = []string{"!=", "<=", ">=", ":", "=", "<", ">", "~"}
+
+var keywords = map[string]tokenKind{
+	"and": tokAnd,
+	"or":  tokOr,
+	"not": tokNot,
+}
+
+// lex splits a query into tokens, ending with a tokEOF.
+func lex(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+
+		case r == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+
+		case r == '"':
+			text, end, err := lexString(input, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokString, text, i})
+			i = end
+
+		case isOperatorStart(r):
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(input[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, errorAt(input, i, "unexpected %q; did you mean \"!=\"?", r)
+			}
+			tokens = append(tokens, token{tokOp, op, i})
+			i += len(op)
+
+		default:
+			start := i
+			for i < len(input) {
+				r, size := utf8.DecodeRuneInString(input[i:])
+				if unicode.IsSpace(r) || r == '(' || r == ')' || r == '"' || isOperatorStart(r) {
+					break
+				}
+				i += size
+			}
+			word := input[start:i]
+			kind := tokWord
+			if k, ok := keywords[strings.ToLower(word)]; ok {
+				kind = k
+			}
+			tokens = append(tokens, token{kind, word, start})
+		}
+	}
+
+	return append(tokens, token{tokEOF, "", len(input)}), nil
+}
+
+// lexString reads a double-quoted string starting at input[start]. Inside it,
+// a backslash escapes a quote or another backslash. It returns the unquoted
+// text and the offset just past the closing quote.
+func lexString(input string, start int) (string, int, error) {
+	var b strings.Builder
+	for i := start + 1; i < len(input); i++ {
+		switch c := input[i]; {
+		case c == '"':
+			return b.String(), i + 1, nil
+		case c == '\\' && i+1 < len(input) && (input[i+1] == '"' || input[i+1] == '\\'):
+			b.WriteByte(input[i+1])
+			i++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, errorAt(input, start, "unterminated string")
+}
+
+func isOperatorStart(r rune) bool {
+	return strings.ContainsRune(":=!<>~", r)
+}
@@ -0,0 +1,185 @@
+package query
+
+import "strings"
+
+// parser builds an Expr from tokens by recursive descent. Its grammar, from
+// the loosest binding operator to the tightest:
+//
+//	or      = and { "or" and }
+//	and     = unary { ["and"] unary }
+//	unary   = "not" unary | primary
+//	primary = "(" or ")" | field op value | +tag | -tag | word | "string"
+type parser struct {
+	input  string
+	tokens []token
+	i      int
+}
+
+// parse parses a whole query.
+func parse(input string) (Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{input: input, tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return nil, errorAt(input, 0, "empty query")
+	}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.unexpected(t)
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{Op: "or", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch p.peek().kind {
+		case tokAnd:
+			p.next()
+		case tokWord, tokString, tokLParen, tokNot:
+			// Terms written next to each other must all match
+		default:
+			return left, nil
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{Op: "and", Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if t := p.peek(); t.kind == tokNot {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{At: t.pos, X: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokLParen:
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.peek(); closing.kind != tokRParen {
+			return nil, errorAt(p.input, closing.pos, "expected \")\" to close the \"(\" at column %d, found %s",
+				column(p.input, t.pos), closing.describe())
+		}
+		p.next()
+		return expr, nil
+
+	case tokString:
+		p.next()
+		return &Text{At: t.pos, Text: t.text}, nil
+
+	case tokWord:
+		p.next()
+		if op := p.peek(); op.kind == tokOp {
+			p.next()
+			value := p.next()
+			if value.kind != tokWord && value.kind != tokString {
+				return nil, errorAt(p.input, value.pos, "expected a value after \"%s%s\", found %s", t.text, op.text, value.describe())
+			}
+			return &Compare{
+				Field:    strings.ToLower(t.text),
+				Op:       op.text,
+				Value:    value.text,
+				FieldPos: t.pos,
+				OpPos:    op.pos,
+				ValuePos: value.pos,
+			}, nil
+		}
+
+		switch {
+		case strings.HasPrefix(t.text, "+"):
+			return p.tag(t, t.text[1:])
+		case strings.HasPrefix(t.text, "-"):
+			tag, err := p.tag(t, t.text[1:])
+			if err != nil {
+				return nil, err
+			}
+			return &Not{At: t.pos, X: tag}, nil
+		}
+		return &Text{At: t.pos, Text: t.text}, nil
+	}
+
+	return nil, p.unexpected(t)
+}
+
+func (p *parser) tag(t token, name string) (Expr, error) {
+	if name == "" {
+		return nil, errorAt(p.input, t.pos, "expected a tag name after %q", t.text)
+	}
+	return &Tag{At: t.pos, Tag: name}, nil
+}
+
+// unexpected reports a token that cannot appear where it was found.
+func (p *parser) unexpected(t token) error {
+	switch t.kind {
+	case tokEOF:
+		if p.i > 0 {
+			return errorAt(p.input, t.pos, "expected a condition after %s", p.tokens[p.i-1].describe())
+		}
+		return errorAt(p.input, t.pos, "expected a condition")
+	case tokAnd, tokOr:
+		if p.i > 0 {
+			return errorAt(p.input, t.pos, "expected a condition after %s, found %s", p.tokens[p.i-1].describe(), t.describe())
+		}
+		return errorAt(p.input, t.pos, "expected a condition before %s", t.describe())
+	case tokOp:
+		return errorAt(p.input, t.pos, "unexpected %s; put a field name before it, e.g. status:open", t.describe())
+	case tokRParen:
+		return errorAt(p.input, t.pos, "unexpected \")\" without a matching \"(\"")
+	}
+	return errorAt(p.input, t.pos, "unexpected %s", t.describe())
+}
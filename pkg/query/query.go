@@ -0,0 +1,144 @@
+// Package query implements a small expression language for selecting tasks,
+// such as
+//
+//	status:open and (tag:backend or priority>=high) and due<+7d and desc~"login"
+//
+// A query is made of conditions combined with "and", "or", "not" and
+// parentheses; conditions written next to each other must all match. A
+// condition compares a field with a value using one of
+//
+//	:  =      matches (":" and "=" are the same)
+//	!=        does not match
+//	< <= > >= orders priorities, dates and IDs
+//	~         the description contains the value, ignoring case
+//
+// The fields are status (a status name, open or closed), priority, tag,
+// project (which includes its sub-projects), desc, due, created, updated,
+// completed, id and parent. Dates accept everything the dateparse package
+// does, such as today, fri or +7d, and compare by day. Fields that a task may
+// lack accept none, as in due:none.
+//
+// A bare word or quoted string matches tasks whose description contains it,
+// and +tag or -tag keeps tasks with or without a tag.
+package query
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/amit9838/taskmanager/internal/task"
+)
+
+// Query is a parsed query, ready to be matched against tasks.
+type Query struct {
+	// Expr is the syntax tree of the query.
+	Expr  Expr
+	match matcher
+}
+
+// Parse parses and checks a query. Relative dates in it are resolved against now.
+// Errors are reported as an *Error locating the problem in the input.
+func Parse(input string, now time.Time) (*Query, error) {
+	expr, err := parse(input)
+	if err != nil {
+		return nil, err
+	}
+
+	match, err := compile(input, expr, now)
+	if err != nil {
+		return nil, err
+	}
+	return &Query{Expr: expr, match: match}, nil
+}
+
+// Match reports whether the task satisfies the query.
+func (q *Query) Match(t task.Task) bool {
+	return q.match(t)
+}
+
+func (q *Query) String() string {
+	return q.Expr.String()
+}
+
+// Tags returns the tags that every matching task carries and the tags that no
+// matching task carries, as far as the +tag, -tag and tag: conditions joined by
+// "and" at the top of the query tell. Repositories with a tag index can use
+// them to narrow the tasks before matching the whole query.
+func (q *Query) Tags() (include, exclude []string) {
+	var walk func(e Expr, negated bool)
+	walk = func(e Expr, negated bool) {
+		var tag string
+		switch e := e.(type) {
+		case *Binary:
+			if e.Op == "and" && !negated {
+				walk(e.Left, false)
+				walk(e.Right, false)
+			}
+			return
+		case *Not:
+			if !negated {
+				walk(e.X, true)
+			}
+			return
+		case *Tag:
+			tag = e.Tag
+		case *Compare:
+			field := e.Field
+			if alias, ok := aliases[field]; ok {
+				field = alias
+			}
+			if field != "tag" || strings.EqualFold(e.Value, "none") || (e.Op != ":" && e.Op != "=" && e.Op != "!=") {
+				return
+			}
+			tag = strings.TrimPrefix(e.Value, "+")
+			negated = negated != (e.Op == "!=")
+		default:
+			return
+		}
+
+		// Tags were checked when the query was compiled
+		tag, _ = task.NormalizeTag(tag)
+		if negated {
+			exclude = append(exclude, tag)
+		} else {
+			include = append(include, tag)
+		}
+	}
+
+	walk(q.Expr, false)
+	return include, exclude
+}
+
+// Error is a problem found in a query, located at a byte offset of the input.
+type Error struct {
+	Query string
+	Pos   int
+	Msg   string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column(), e.Msg)
+}
+
+// Column returns the 1-based column, counted in characters, of the problem.
+func (e *Error) Column() int {
+	return column(e.Query, e.Pos)
+}
+
+// Context renders the query with a caret under the offending column:
+//
+//	status:open and due<soon
+//	                    ^
+func (e *Error) Context() string {
+	return e.Query + "\n" + strings.Repeat(" ", e.Column()-1) + "^"
+}
+
+func errorAt(input string, pos int, format string, args ...any) *Error {
+	return &Error{Query: input, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func column(input string, pos int) int {
+	return utf8.RuneCountInString(input[:pos]) + 1
+}
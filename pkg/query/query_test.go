@@ -0,0 +1,190 @@
+package query
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/amit9838/taskmanager/internal/task"
+)
+
+// TestParse tests operator precedence and the rendering of parsed queries
+func TestParse(t *testing.T) {
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{"status:open", "status:open"},
+		{"a or b and c", "(a or (b and c))"},
+		{"(a or b) c", "((a or b) and c)"},
+		{"not a or b", "(not a or b)"},
+		{"NOT (a OR b)", "not (a or b)"},
+		{"+backend -docs", "(+backend and not +docs)"},
+		{`desc~"fix the login"`, `desc~"fix the login"`},
+		{`"and"`, `"and"`},
+		{"priority>=high due<+7d", "(priority>=high and due<+7d)"},
+		{`desc~"say \"hi\""`, `desc~"say \"hi\""`},
+	}
+
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.Local)
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			q, err := Parse(c.input, now)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if q.String() != c.want {
+				t.Errorf("Expected %s, got %s", c.want, q.String())
+			}
+		})
+	}
+}
+
+// TestMatch tests evaluating queries against tasks
+func TestMatch(t *testing.T) {
+	// Wednesday
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.Local)
+	day := func(days int) *time.Time {
+		d := time.Date(2026, 10, 14+days, 9, 0, 0, 0, time.Local)
+		return &d
+	}
+
+	tasks := []task.Task{
+		{ID: 1, Description: "Fix login page", Status: task.StatusPending, Priority: task.PriorityHigh,
+			Tags: []string{"backend"}, Project: "work.web", DueAt: day(2), CreatedAt: *day(-7)},
+		{ID: 2, Description: "Write docs", Status: task.StatusInProgress, Priority: task.PriorityLow,
+			Tags: []string{"docs"}, Project: "work", DueAt: day(10), CreatedAt: *day(-1)},
+		{ID: 3, Description: "Login audit", Status: task.StatusBlocked, Priority: task.PriorityLow,
+			Tags: []string{"backend"}, ParentID: 2, CreatedAt: *day(0)},
+		{ID: 4, Description: "Old thing", Status: task.StatusDone, CompletedAt: day(-1), DueAt: day(-3), CreatedAt: *day(-30)},
+	}
+
+	cases := []struct {
+		query string
+		want  []int
+	}{
+		{`status:open and (tag:backend or priority>=high) and due<+7d and desc~"login"`, []int{1}},
+		{"status:open", []int{1, 2, 3}},
+		{"status:closed", []int{4}},
+		{"status!=blocked", []int{1, 2, 4}},
+		{"login", []int{1, 3}},
+		{`"login page"`, []int{1}},
+		{"+backend -docs", []int{1, 3}},
+		{"tag:none", []int{4}},
+		{"priority>low", []int{1}},
+		{"priority<=low", []int{2, 3, 4}},
+		{"project:work", []int{1, 2}},
+		{"project:work.web", []int{1}},
+		{"project:none", []int{3, 4}},
+		{"due:none", []int{3}},
+		{"due!=none", []int{1, 2, 4}},
+		{"due:fri", []int{1}},
+		{"due<=fri", []int{1, 4}},
+		{"due>fri", []int{2}},
+		{"created>=-1d", []int{2, 3}},
+		{"completed:yesterday", []int{4}},
+		{"id>2", []int{3, 4}},
+		{"parent:2", []int{3}},
+		{"not (login or docs)", []int{4}},
+		{"pri:high or tags:docs", []int{1, 2}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.query, func(t *testing.T) {
+			q, err := Parse(c.query, now)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			var got []int
+			for _, task := range tasks {
+				if q.Match(task) {
+					got = append(got, task.ID)
+				}
+			}
+			if len(got) != len(c.want) {
+				t.Fatalf("Expected tasks %v, got %v", c.want, got)
+			}
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Fatalf("Expected tasks %v, got %v", c.want, got)
+				}
+			}
+		})
+	}
+}
+
+// TestTags tests extracting the tags a query requires or rules out
+func TestTags(t *testing.T) {
+	cases := []struct {
+		input            string
+		include, exclude string
+	}{
+		{"+backend -docs", "[backend]", "[docs]"},
+		{"status:open and tag:API tags!=ui", "[api]", "[ui]"},
+		{"not +a and not (tag:b)", "[]", "[a b]"},
+		{"+a or +b", "[]", "[]"},
+		{"not (+a and +b)", "[]", "[]"},
+		{"tag:none login", "[]", "[]"},
+	}
+
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.Local)
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			q, err := Parse(c.input, now)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			include, exclude := q.Tags()
+			if fmt.Sprint(include) != c.include || fmt.Sprint(exclude) != c.exclude {
+				t.Errorf("Expected %s and %s, got %v and %v", c.include, c.exclude, include, exclude)
+			}
+		})
+	}
+}
+
+// TestErrors tests that invalid queries report the column of the problem
+func TestErrors(t *testing.T) {
+	cases := []struct {
+		input  string
+		column int
+	}{
+		{"", 1},
+		{"stat:open", 1},
+		{"status:opne", 8},
+		{"status:open and due<soon", 21},
+		{"priority~high", 9},
+		{"(tag:x or", 10},
+		{"tag:x )", 7},
+		{`desc~"login`, 6},
+		{"a and and b", 7},
+		{"due<", 5},
+		{"a ! b", 3},
+		{"+", 1},
+		{"due>none", 5},
+		{"id:abc", 4},
+		{"ünïcode stat:x", 9},
+	}
+
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.Local)
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			_, err := Parse(c.input, now)
+			qerr, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("Expected a query error, got %v", err)
+			}
+			if qerr.Column() != c.column {
+				t.Errorf("Expected column %d, got %d (%v)", c.column, qerr.Column(), qerr)
+			}
+		})
+	}
+
+	t.Run("Context points at the column", func(t *testing.T) {
+		_, err := Parse("status:open and due<soon", now)
+		want := "status:open and due<soon\n                    ^"
+		if got := err.(*Error).Context(); got != want {
+			t.Errorf("Expected\n%s\ngot\n%s", want, got)
+		}
+	})
+}